@@ -0,0 +1,379 @@
+package btree
+
+// ArrayDegree is the degree of an ArrayBTree.  It is a constant since it sets
+// the size of the arrays holding the items and children of its nodes.
+const ArrayDegree = 16
+
+const (
+	arrayMaxItems = 2*ArrayDegree - 1
+	arrayMinItems = ArrayDegree - 1
+)
+
+// arrayNode is a node of an ArrayBTree.  Leaves have no children.
+type arrayNode[T Item[T]] struct {
+	n        int
+	items    [arrayMaxItems]T
+	children [arrayMaxItems + 1]*arrayNode[T]
+}
+
+func (n *arrayNode[T]) leaf() bool {
+	return n.children[0] == nil
+}
+
+// find returns the index where the given item should be inserted into the
+// node, and whether an equal item is already there.
+func (n *arrayNode[T]) find(item T) (int, bool) {
+	return items[T](n.items[:n.n]).find(item)
+}
+
+func (n *arrayNode[T]) insertItemAt(i int, item T) {
+	copy(n.items[i+1:n.n+1], n.items[i:n.n])
+	n.items[i] = item
+	n.n++
+}
+
+func (n *arrayNode[T]) removeItemAt(i int) T {
+	item := n.items[i]
+	copy(n.items[i:n.n-1], n.items[i+1:n.n])
+	n.n--
+	var zero T
+	n.items[n.n] = zero
+	return item
+}
+
+// insertChildAt inserts a child at index i, the node must have been given its
+// new item already.
+func (n *arrayNode[T]) insertChildAt(i int, child *arrayNode[T]) {
+	copy(n.children[i+1:n.n+1], n.children[i:n.n])
+	n.children[i] = child
+}
+
+// removeChildAt removes the child at index i, the node must have lost an item
+// already.
+func (n *arrayNode[T]) removeChildAt(i int) *arrayNode[T] {
+	child := n.children[i]
+	copy(n.children[i:n.n+1], n.children[i+1:n.n+2])
+	n.children[n.n+1] = nil
+	return child
+}
+
+// split splits the node at index i, the node keeps the items before i and the
+// returned node gets the items after it.
+func (n *arrayNode[T]) split(i int) (T, *arrayNode[T]) {
+	item := n.items[i]
+	next := &arrayNode[T]{n: n.n - i - 1}
+	copy(next.items[:], n.items[i+1:n.n])
+	if !n.leaf() {
+		copy(next.children[:], n.children[i+1:n.n+1])
+		for j := i + 1; j <= n.n; j++ {
+			n.children[j] = nil
+		}
+	}
+	var zero T
+	for j := i; j < n.n; j++ {
+		n.items[j] = zero
+	}
+	n.n = i
+	return item, next
+}
+
+// maybeSplitChild splits child i if it is full, and returns whether it did.
+func (n *arrayNode[T]) maybeSplitChild(i int) bool {
+	if n.children[i].n < arrayMaxItems {
+		return false
+	}
+	item, next := n.children[i].split(arrayMaxItems / 2)
+	n.insertItemAt(i, item)
+	n.insertChildAt(i+1, next)
+	return true
+}
+
+func (n *arrayNode[T]) insert(item T) (_ T, _ bool) {
+	i, found := n.find(item)
+	if found {
+		out := n.items[i]
+		n.items[i] = item
+		return out, true
+	}
+	if n.leaf() {
+		n.insertItemAt(i, item)
+		return
+	}
+	if n.maybeSplitChild(i) {
+		inTree := n.items[i]
+		switch {
+		case item.Less(inTree):
+			// no change, we want first split node
+		case inTree.Less(item):
+			i++ // we want second split node
+		default:
+			n.items[i] = item
+			return inTree, true
+		}
+	}
+	return n.children[i].insert(item)
+}
+
+func (n *arrayNode[T]) remove(item T, typ toRemove) (_ T, _ bool) {
+	var i int
+	var found bool
+	switch typ {
+	case removeMax:
+		if n.leaf() {
+			return n.removeItemAt(n.n - 1), true
+		}
+		i = n.n
+	case removeMin:
+		if n.leaf() {
+			return n.removeItemAt(0), true
+		}
+		i = 0
+	case removeItem:
+		i, found = n.find(item)
+		if n.leaf() {
+			if found {
+				return n.removeItemAt(i), true
+			}
+			return
+		}
+	}
+	if n.children[i].n <= arrayMinItems {
+		n.growChild(i)
+		return n.remove(item, typ)
+	}
+	if found {
+		// Replace the item by its predecessor.
+		out := n.items[i]
+		var zero T
+		n.items[i], _ = n.children[i].remove(zero, removeMax)
+		return out, true
+	}
+	return n.children[i].remove(item, typ)
+}
+
+// growChild makes sure child i can spare an item, by stealing one from a
+// sibling or by merging it with a sibling.  See node.growChildAndRemove.
+func (n *arrayNode[T]) growChild(i int) {
+	switch {
+	case i > 0 && n.children[i-1].n > arrayMinItems:
+		// Steal from left child.
+		child, stealFrom := n.children[i], n.children[i-1]
+		child.insertItemAt(0, n.items[i-1])
+		if !stealFrom.leaf() {
+			child.insertChildAt(0, stealFrom.children[stealFrom.n])
+			stealFrom.children[stealFrom.n] = nil
+		}
+		n.items[i-1] = stealFrom.removeItemAt(stealFrom.n - 1)
+	case i < n.n && n.children[i+1].n > arrayMinItems:
+		// Steal from right child.
+		child, stealFrom := n.children[i], n.children[i+1]
+		child.items[child.n] = n.items[i]
+		child.n++
+		if !stealFrom.leaf() {
+			child.children[child.n] = stealFrom.children[0]
+		}
+		n.items[i] = stealFrom.removeItemAt(0)
+		if !stealFrom.leaf() {
+			stealFrom.removeChildAt(0)
+		}
+	default:
+		// Merge with right child.
+		if i >= n.n {
+			i--
+		}
+		child := n.children[i]
+		child.items[child.n] = n.removeItemAt(i)
+		merge := n.removeChildAt(i + 1)
+		copy(child.items[child.n+1:], merge.items[:merge.n])
+		copy(child.children[child.n+1:], merge.children[:merge.n+1])
+		child.n += merge.n + 1
+	}
+}
+
+func (n *arrayNode[T]) ascend(start, stop optionalItem[T], iterator ItemIterator[T]) bool {
+	i := 0
+	if start.valid {
+		i, _ = n.find(start.item)
+	}
+	for ; i < n.n; i++ {
+		if !n.leaf() && !n.children[i].ascend(start, stop, iterator) {
+			return false
+		}
+		if stop.valid && !n.items[i].Less(stop.item) {
+			return false
+		}
+		if !iterator(n.items[i]) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return n.children[n.n].ascend(start, stop, iterator)
+	}
+	return true
+}
+
+func (n *arrayNode[T]) descend(iterator ItemIterator[T]) bool {
+	for i := n.n; i >= 0; i-- {
+		if i < n.n && !iterator(n.items[i]) {
+			return false
+		}
+		if !n.leaf() && !n.children[i].descend(iterator) {
+			return false
+		}
+	}
+	return true
+}
+
+// ArrayBTree is a B-Tree of degree ArrayDegree whose nodes hold their items
+// and children in fixed-size arrays rather than in slices.  This spares the
+// slice headers and the reallocations of growing slices, and keeps the items
+// of a node next to its other fields in memory, at the cost of allocating
+// full-size nodes, children included for leaves.
+//
+// It doesn't support FreeLists, copies, nor the extensions of BTree.
+type ArrayBTree[T Item[T]] struct {
+	root   *arrayNode[T]
+	length int
+}
+
+// NewArray creates a new ArrayBTree.
+func NewArray[T Item[T]]() *ArrayBTree[T] {
+	return &ArrayBTree[T]{}
+}
+
+// ReplaceOrInsert adds the given item to the tree.  If an item in the tree
+// already equals the given one, it is removed from the tree and returned,
+// and the second return value is true.  Otherwise, (zeroValue, false)
+func (t *ArrayBTree[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
+	if t.root == nil {
+		t.root = &arrayNode[T]{}
+	}
+	if t.root.n >= arrayMaxItems {
+		item2, second := t.root.split(arrayMaxItems / 2)
+		root := &arrayNode[T]{n: 1}
+		root.items[0] = item2
+		root.children[0], root.children[1] = t.root, second
+		t.root = root
+	}
+	out, outb := t.root.insert(item)
+	if !outb {
+		t.length++
+	}
+	return out, outb
+}
+
+func (t *ArrayBTree[T]) deleteItem(item T, typ toRemove) (_ T, _ bool) {
+	if t.root == nil || t.root.n == 0 {
+		return
+	}
+	out, outb := t.root.remove(item, typ)
+	if t.root.n == 0 && !t.root.leaf() {
+		t.root = t.root.children[0]
+	}
+	if outb {
+		t.length--
+	}
+	return out, outb
+}
+
+// Delete removes an item equal to the passed in item from the tree, returning
+// it.  If no such item exists, returns (zeroValue, false).
+func (t *ArrayBTree[T]) Delete(item T) (T, bool) {
+	return t.deleteItem(item, removeItem)
+}
+
+// DeleteMin removes the smallest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *ArrayBTree[T]) DeleteMin() (T, bool) {
+	var zero T
+	return t.deleteItem(zero, removeMin)
+}
+
+// DeleteMax removes the largest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *ArrayBTree[T]) DeleteMax() (T, bool) {
+	var zero T
+	return t.deleteItem(zero, removeMax)
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item.
+func (t *ArrayBTree[T]) Get(key T) (_ T, _ bool) {
+	for n := t.root; n != nil; {
+		i, found := n.find(key)
+		if found {
+			return n.items[i], true
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// Has returns true if the given key is in the tree.
+func (t *ArrayBTree[T]) Has(key T) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the
+// tree is empty.
+func (t *ArrayBTree[T]) Min() (_ T, _ bool) {
+	if t.root == nil || t.root.n == 0 {
+		return
+	}
+	n := t.root
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.items[0], true
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the tree
+// is empty.
+func (t *ArrayBTree[T]) Max() (_ T, _ bool) {
+	if t.root == nil || t.root.n == 0 {
+		return
+	}
+	n := t.root
+	for !n.leaf() {
+		n = n.children[n.n]
+	}
+	return n.items[n.n-1], true
+}
+
+// Len returns the number of items currently in the tree.
+func (t *ArrayBTree[T]) Len() int {
+	return t.length
+}
+
+// AscendRange calls the iterator for every value in the tree within the range
+// [greaterOrEqual, lessThan), until iterator returns false.
+func (t *ArrayBTree[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIterator[T]) {
+	if t.root != nil {
+		t.root.ascend(optional(greaterOrEqual), optional(lessThan), iterator)
+	}
+}
+
+// AscendGreaterOrEqual calls the iterator for every value in the tree within
+// the range [pivot, last], until iterator returns false.
+func (t *ArrayBTree[T]) AscendGreaterOrEqual(pivot T, iterator ItemIterator[T]) {
+	if t.root != nil {
+		t.root.ascend(optional(pivot), empty[T](), iterator)
+	}
+}
+
+// Ascend calls the iterator for every value in the tree within the range
+// [first, last], until iterator returns false.
+func (t *ArrayBTree[T]) Ascend(iterator ItemIterator[T]) {
+	if t.root != nil {
+		t.root.ascend(empty[T](), empty[T](), iterator)
+	}
+}
+
+// Descend calls the iterator for every value in the tree within the range
+// [last, first], until iterator returns false.
+func (t *ArrayBTree[T]) Descend(iterator ItemIterator[T]) {
+	if t.root != nil {
+		t.root.descend(iterator)
+	}
+}
@@ -0,0 +1,194 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checkArrayNode checks the node sizes and the ordering of the subtree of n,
+// and returns its height.
+func checkArrayNode(t *testing.T, n *arrayNode[*testInt], root bool, lo, hi *testInt) int {
+	t.Helper()
+	if !root && n.n < arrayMinItems || n.n > arrayMaxItems {
+		t.Fatalf("node has %v items", n.n)
+	}
+	for i := 0; i < n.n; i++ {
+		if lo != nil && !lo.Less(n.items[i]) || hi != nil && !n.items[i].Less(hi) {
+			t.Fatalf("item %v out of [%v, %v]", n.items[i], lo, hi)
+		}
+		if i > 0 && !n.items[i-1].Less(n.items[i]) {
+			t.Fatalf("items %v and %v out of order", n.items[i-1], n.items[i])
+		}
+	}
+	for i := n.n; i < arrayMaxItems; i++ {
+		if n.items[i] != nil {
+			t.Fatalf("vacated item %v wasn't cleared", i)
+		}
+	}
+	if n.leaf() {
+		for _, c := range n.children {
+			if c != nil {
+				t.Fatal("leaf has children")
+			}
+		}
+		return 1
+	}
+	height := 0
+	for i := 0; i <= n.n; i++ {
+		clo, chi := lo, hi
+		if i > 0 {
+			clo = n.items[i-1]
+		}
+		if i < n.n {
+			chi = n.items[i]
+		}
+		h := checkArrayNode(t, n.children[i], false, clo, chi)
+		if i > 0 && h != height {
+			t.Fatalf("children have heights %v and %v", height, h)
+		}
+		height = h
+	}
+	for i := n.n + 1; i <= arrayMaxItems; i++ {
+		if n.children[i] != nil {
+			t.Fatalf("vacated child %v wasn't cleared", i)
+		}
+	}
+	return height + 1
+}
+
+func TestArrayBTree(t *testing.T) {
+	tr := NewArray[*testInt]()
+	model := map[int]bool{}
+	for i := 0; i < 20000; i++ {
+		v := rand.Intn(2000)
+		switch rand.Intn(6) {
+		case 0, 1, 2:
+			_, ok := tr.ReplaceOrInsert(newTestInt(v))
+			if ok != model[v] {
+				t.Fatalf("insert %v: got %v", v, ok)
+			}
+			model[v] = true
+		case 3:
+			_, ok := tr.Delete(newTestInt(v))
+			if ok != model[v] {
+				t.Fatalf("delete %v: got %v", v, ok)
+			}
+			delete(model, v)
+		case 4:
+			if min, ok := tr.DeleteMin(); ok {
+				delete(model, int(*min))
+			}
+		case 5:
+			if max, ok := tr.DeleteMax(); ok {
+				delete(model, int(*max))
+			}
+		}
+		if tr.Len() != len(model) {
+			t.Fatalf("len %v, want %v", tr.Len(), len(model))
+		}
+		if i%500 == 0 && tr.root != nil {
+			checkArrayNode(t, tr.root, true, nil, nil)
+		}
+	}
+	if tr.root != nil {
+		checkArrayNode(t, tr.root, true, nil, nil)
+	}
+
+	var want []int
+	for v := range model {
+		want = append(want, v)
+	}
+	sort.Ints(want)
+	var got []int
+	tr.Ascend(func(i *testInt) bool {
+		got = append(got, int(*i))
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ascend\n got: %v\nwant: %v", got, want)
+	}
+	if len(want) > 0 {
+		if min, _ := tr.Min(); int(*min) != want[0] {
+			t.Fatalf("min %v, want %v", *min, want[0])
+		}
+		if max, _ := tr.Max(); int(*max) != want[len(want)-1] {
+			t.Fatalf("max %v, want %v", *max, want[len(want)-1])
+		}
+	}
+	got = got[:0]
+	tr.Descend(func(i *testInt) bool {
+		got = append(got, int(*i))
+		return true
+	})
+	for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+		want[i], want[j] = want[j], want[i]
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("descend\n got: %v\nwant: %v", got, want)
+	}
+
+	for i := 0; i < 100; i++ {
+		lo, hi := rand.Intn(2000), rand.Intn(2000)
+		want = want[:0]
+		for v := range model {
+			if v >= lo && v < hi {
+				want = append(want, v)
+			}
+		}
+		sort.Ints(want)
+		got = got[:0]
+		tr.AscendRange(newTestInt(lo), newTestInt(hi), func(i *testInt) bool {
+			got = append(got, int(*i))
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ascend range [%v, %v)\n got: %v\nwant: %v", lo, hi, got, want)
+		}
+		if tr.Has(newTestInt(lo)) != model[lo] {
+			t.Fatalf("has %v: got %v", lo, !model[lo])
+		}
+	}
+
+	for tr.Len() > 0 {
+		tr.DeleteMin()
+	}
+	if _, ok := tr.Min(); ok {
+		t.Fatal("empty tree has a min")
+	}
+}
+
+func BenchmarkInsertArray(b *testing.B) {
+	b.StopTimer()
+	insertP := rand.Perm(benchmarkTreeSize)
+	b.StartTimer()
+	i := 0
+	for i < b.N {
+		tr := NewArray[*testInt]()
+		for _, item := range insertP {
+			tr.ReplaceOrInsert(newTestInt(item))
+			i++
+			if i >= b.N {
+				return
+			}
+		}
+	}
+}
+
+func BenchmarkSeekArray(b *testing.B) {
+	b.StopTimer()
+	size := 100000
+	insertP := rand.Perm(size)
+	tr := NewArray[*testInt]()
+	for _, item := range insertP {
+		tr.ReplaceOrInsert(newTestInt(item))
+	}
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		tr.AscendGreaterOrEqual(newTestInt(i%size), func(i *testInt) bool { return false })
+	}
+}
@@ -0,0 +1,136 @@
+package btree
+
+// Augmenter computes the summaries kept by the nodes of an AugmentedBTree.
+//
+// The summary of a subtree is obtained by combining, in order, the summaries
+// of its items, so Combine must be associative, but it needn't be
+// commutative.
+type Augmenter[T Item[T], A any] interface {
+	// Summarize returns the summary of a single item.
+	Summarize(item T) A
+	// Combine returns the summary of two consecutive runs of items given the
+	// summary of each run.
+	Combine(a, b A) A
+}
+
+// augmenter updates the summary of a node from its items and the summaries
+// of its children.
+type augmenter[T Item[T]] interface {
+	update(n *node[T])
+}
+
+// AugmentedBTree is a BTree whose nodes keep a summary of their subtree, as
+// computed by an Augmenter, up to date through every modification of the
+// tree.  This allows aggregating any range of items in O(log n).
+//
+// Copies of an AugmentedBTree made with DeepCopy and the like are plain,
+// non-augmented, BTrees.
+type AugmentedBTree[T Item[T], A any] struct {
+	*BTree[T]
+	augmenter Augmenter[T, A]
+}
+
+// NewAugmented creates a new B-Tree with the given degree whose nodes
+// maintain the summaries computed by aug.
+func NewAugmented[T Item[T], A any](degree int, aug Augmenter[T, A]) *AugmentedBTree[T, A] {
+	t := &AugmentedBTree[T, A]{
+		BTree:     New[T](degree),
+		augmenter: aug,
+	}
+	t.BTree.aug = t
+
+	return t
+}
+
+// summary accumulates summaries, in order.
+type summary[T Item[T], A any] struct {
+	augmenter Augmenter[T, A]
+	value     A
+	valid     bool
+}
+
+func (s *summary[T, A]) add(a A) {
+	if s.valid {
+		s.value = s.augmenter.Combine(s.value, a)
+	} else {
+		s.value, s.valid = a, true
+	}
+}
+
+// addNode adds the summary of the subtree rooted at n, if it isn't empty.
+func (s *summary[T, A]) addNode(n *node[T]) {
+	if a, ok := n.summary.(A); ok {
+		s.add(a)
+	}
+}
+
+func (t *AugmentedBTree[T, A]) update(n *node[T]) {
+	s := summary[T, A]{augmenter: t.augmenter}
+	for i, item := range n.items {
+		if len(n.children) > 0 {
+			s.addNode(n.children[i])
+		}
+		s.add(t.augmenter.Summarize(item))
+	}
+	if len(n.children) > 0 {
+		s.addNode(n.children[len(n.children)-1])
+	}
+
+	if s.valid {
+		n.summary = s.value
+	} else {
+		n.summary = nil
+	}
+}
+
+// Aggregate returns the combined summary of the items of the tree within the
+// range between lo and hi, or (zeroValue, false) if there are none.
+func (t *AugmentedBTree[T, A]) Aggregate(lo, hi Bound[T]) (A, bool) {
+	s := summary[T, A]{augmenter: t.augmenter}
+	if t.root != nil {
+		t.aggregate(t.root, lo, hi, &s)
+	}
+
+	return s.value, s.valid
+}
+
+func (t *AugmentedBTree[T, A]) aggregate(n *node[T], lo, hi Bound[T], s *summary[T, A]) {
+	n.walkRange(lo, hi, s.addNode, func(item T) {
+		s.add(t.augmenter.Summarize(item))
+	})
+}
+
+// walkRange visits the items of the subtree of n between lo and hi, calling
+// node with the subtrees entirely within the range and item with the other
+// items of the range.
+func (n *node[T]) walkRange(lo, hi Bound[T], node func(*node[T]), item func(T)) {
+	if lo.Unbounded && hi.Unbounded {
+		node(n)
+		return
+	}
+
+	unbounded := Unbounded[T]()
+	for i := 0; i <= len(n.items); i++ {
+		// Child i holds the items between items[i-1] and items[i].
+		if len(n.children) > 0 {
+			clo, chi := lo, hi
+			switch {
+			case i > 0 && !hi.Unbounded && !n.items[i-1].Less(hi.Item):
+				return
+			case i < len(n.items) && !lo.Unbounded && !lo.Item.Less(n.items[i]):
+				// The whole child is below lo.
+			default:
+				if i > 0 && (lo.Unbounded || !n.items[i-1].Less(lo.Item)) {
+					clo = unbounded
+				}
+				if i < len(n.items) && (hi.Unbounded || !hi.Item.Less(n.items[i])) {
+					chi = unbounded
+				}
+				n.children[i].walkRange(clo, chi, node, item)
+			}
+		}
+		if i < len(n.items) && n.t.above(lo, n.items[i]) && n.t.below(hi, n.items[i]) {
+			item(n.items[i])
+		}
+	}
+}
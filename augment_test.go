@@ -0,0 +1,126 @@
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testSummary is the summary of a run of testInts.
+type testSummary struct {
+	sum, count  int
+	first, last int
+}
+
+type testAugmenter struct{}
+
+func (testAugmenter) Summarize(item *testInt) testSummary {
+	return testSummary{sum: int(*item), count: 1, first: int(*item), last: int(*item)}
+}
+
+func (testAugmenter) Combine(a, b testSummary) testSummary {
+	return testSummary{sum: a.sum + b.sum, count: a.count + b.count, first: a.first, last: b.last}
+}
+
+// checkSummaries verifies the summary of every node of tr against the items
+// of its subtree.
+func checkSummaries(t *testing.T, tr *AugmentedBTree[*testInt, testSummary]) {
+	t.Helper()
+	var walk func(n *node[*testInt]) []*testInt
+	walk = func(n *node[*testInt]) (all []*testInt) {
+		for i, item := range n.items {
+			if len(n.children) > 0 {
+				all = append(all, walk(n.children[i])...)
+			}
+			all = append(all, item)
+		}
+		if len(n.children) > 0 {
+			all = append(all, walk(n.children[len(n.children)-1])...)
+		}
+		got, ok := n.summary.(testSummary)
+		if len(all) == 0 {
+			if ok {
+				t.Fatalf("empty node has summary %+v", got)
+			}
+			return
+		}
+		want := testSummary{first: int(*all[0]), last: int(*all[len(all)-1]), count: len(all)}
+		for _, item := range all {
+			want.sum += int(*item)
+		}
+		if got != want {
+			t.Fatalf("node %v: summary %+v, want %+v", n.items, got, want)
+		}
+		return
+	}
+	if tr.root != nil {
+		walk(tr.root)
+	}
+}
+
+func TestAugmentedBTree(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		tr := NewAugmented[*testInt, testSummary](degree, testAugmenter{})
+		for i := 0; i < 2000; i++ {
+			switch v := rand.Intn(500); rand.Intn(8) {
+			case 0, 1, 2:
+				tr.ReplaceOrInsert(newTestInt(v))
+			case 3:
+				tr.Delete(newTestInt(v))
+			case 4:
+				tr.DeleteMin()
+			case 5:
+				var batch []*testInt
+				for _, v := range rand.Perm(500)[:rand.Intn(50)] {
+					batch = append(batch, newTestInt(v))
+				}
+				tr.ReplaceOrInsertMany(batch)
+			case 6:
+				var keys []*testInt
+				for _, v := range rand.Perm(500)[:rand.Intn(50)] {
+					keys = append(keys, newTestInt(v))
+				}
+				tr.DeleteMany(keys)
+			case 7:
+				action := UpsertAction(rand.Intn(4))
+				tr.Upsert(newTestInt(v), func(*testInt, bool) (*testInt, UpsertAction) {
+					return newTestInt(v), action
+				})
+			}
+			checkSummaries(t, tr)
+		}
+
+		bounds := []Bound[*testInt]{Unbounded[*testInt]()}
+		for i := 0; i < 20; i++ {
+			bounds = append(bounds, Bound[*testInt]{Item: newTestInt(rand.Intn(520) - 10), Inclusive: rand.Intn(2) == 0})
+		}
+		for _, lo := range bounds {
+			for _, hi := range bounds {
+				want := testSummary{}
+				items := tr.Range(lo, hi, Ascending).Items()
+				for _, item := range items {
+					want.sum += int(*item)
+				}
+				if len(items) > 0 {
+					want.count = len(items)
+					want.first, want.last = int(*items[0]), int(*items[len(items)-1])
+				}
+				got, ok := tr.Aggregate(lo, hi)
+				if ok != (len(items) > 0) || got != want {
+					t.Fatalf("degree %v: aggregate(%+v, %+v): got %+v %v, want %+v", degree, lo, hi, got, ok, want)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkAggregate(b *testing.B) {
+	tr := NewAugmented[*testInt, testSummary](*btreeDegree, testAugmenter{})
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	lo, hi := Bound[*testInt]{Item: newTestInt(100), Inclusive: true}, Bound[*testInt]{Item: newTestInt(10000 - 100)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Aggregate(lo, hi)
+	}
+}
@@ -0,0 +1,209 @@
+package btree
+
+// EvictionPolicy tells which item a BoundedBTree evicts when it is full.
+type EvictionPolicy int
+
+const (
+	EvictMin EvictionPolicy = iota // evicts the lowest item
+	EvictMax                       // evicts the highest item
+	EvictLRU                       // evicts the least recently used item
+)
+
+// boundedEntry is an item of a BoundedBTree, linked to the other entries in
+// recency order.
+type boundedEntry[T Item[T]] struct {
+	item       T
+	prev, next *boundedEntry[T]
+}
+
+func (e *boundedEntry[T]) Less(e2 *boundedEntry[T]) bool {
+	return e.item.Less(e2.item)
+}
+
+// DeepCopy copies the item of the entry but not its links, which only make
+// sense within the BoundedBTree owning the entry.
+func (e *boundedEntry[T]) DeepCopy() *boundedEntry[T] {
+	return &boundedEntry[T]{item: e.item.DeepCopy()}
+}
+
+// BoundedBTree is a BTree holding at most a given number of items.  Adding an
+// item to a full tree evicts another one, or the new item itself, according
+// to the tree's EvictionPolicy.
+//
+// With EvictLRU, items are used when they are added, replaced or returned by
+// Get.
+//
+// As for BTree, read operations are safe for concurrent use, except for Get
+// with EvictLRU which updates the recency of the item it returns.
+type BoundedBTree[T Item[T]] struct {
+	tree     *BTree[*boundedEntry[T]]
+	capacity int
+	policy   EvictionPolicy
+	onEvict  func(T)
+
+	// lru is the sentinel of the circular list of entries, from the most
+	// recently used one at lru.next to the least recently used one at
+	// lru.prev.  It is only maintained for EvictLRU.
+	lru boundedEntry[T]
+}
+
+// NewBounded creates a new B-Tree with the given degree holding at most
+// capacity items.  onEvict, if not nil, is called with every item evicted
+// from the tree.
+func NewBounded[T Item[T]](degree, capacity int, policy EvictionPolicy, onEvict func(T)) *BoundedBTree[T] {
+	if capacity <= 0 {
+		panic("bad capacity")
+	}
+	t := &BoundedBTree[T]{
+		tree:     New[*boundedEntry[T]](degree),
+		capacity: capacity,
+		policy:   policy,
+		onEvict:  onEvict,
+	}
+	t.lru.prev, t.lru.next = &t.lru, &t.lru
+
+	return t
+}
+
+// unlink removes e from the recency list.
+func (t *BoundedBTree[T]) unlink(e *boundedEntry[T]) {
+	if t.policy != EvictLRU {
+		return
+	}
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+// touch moves e, which may not be in the recency list yet, to its front.
+func (t *BoundedBTree[T]) touch(e *boundedEntry[T]) {
+	if t.policy != EvictLRU {
+		return
+	}
+	if e.prev != nil {
+		e.prev.next, e.next.prev = e.next, e.prev
+	}
+	e.prev, e.next = &t.lru, t.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+// lookup returns the entry equal to key.
+func (t *BoundedBTree[T]) lookup(key T) (*boundedEntry[T], bool) {
+	return t.tree.Get(&boundedEntry[T]{item: key})
+}
+
+// ReplaceOrInsert adds the given item to the tree.  If an item in the tree
+// already equals the given one, it is replaced and returned along with true.
+// Otherwise, (zeroValue, false) is returned and, if the tree was full, an item
+// is evicted.
+func (t *BoundedBTree[T]) ReplaceOrInsert(item T) (old T, replaced bool) {
+	entry := &boundedEntry[T]{item: item}
+	t.tree.Upsert(entry, func(e *boundedEntry[T], exists bool) (*boundedEntry[T], UpsertAction) {
+		if exists {
+			// Update the entry in place to keep its position in the recency
+			// list.
+			old, replaced = e.item, true
+			e.item = item
+			t.touch(e)
+			return e, UpsertKeep
+		}
+		t.touch(entry)
+		return entry, UpsertInsert
+	})
+	if t.tree.Len() > t.capacity {
+		t.evict()
+	}
+
+	return old, replaced
+}
+
+// evict removes an item according to the eviction policy.
+func (t *BoundedBTree[T]) evict() {
+	var e *boundedEntry[T]
+	switch t.policy {
+	case EvictMin:
+		e, _ = t.tree.DeleteMin()
+	case EvictMax:
+		e, _ = t.tree.DeleteMax()
+	case EvictLRU:
+		e = t.lru.prev
+		t.tree.Delete(e)
+		t.unlink(e)
+	}
+	if t.onEvict != nil {
+		t.onEvict(e.item)
+	}
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item.
+func (t *BoundedBTree[T]) Get(key T) (_ T, _ bool) {
+	e, ok := t.lookup(key)
+	if !ok {
+		return
+	}
+	t.touch(e)
+	return e.item, true
+}
+
+// Has returns true if the given key is in the tree, without using it.
+func (t *BoundedBTree[T]) Has(key T) bool {
+	_, ok := t.lookup(key)
+	return ok
+}
+
+// Delete removes an item equal to the passed in item from the tree, returning
+// it.  If no such item exists, returns (zeroValue, false).
+func (t *BoundedBTree[T]) Delete(key T) (_ T, _ bool) {
+	e, ok := t.tree.Delete(&boundedEntry[T]{item: key})
+	if !ok {
+		return
+	}
+	t.unlink(e)
+	return e.item, true
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the
+// tree is empty.
+func (t *BoundedBTree[T]) Min() (_ T, _ bool) {
+	e, ok := t.tree.Min()
+	if !ok {
+		return
+	}
+	return e.item, true
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the tree
+// is empty.
+func (t *BoundedBTree[T]) Max() (_ T, _ bool) {
+	e, ok := t.tree.Max()
+	if !ok {
+		return
+	}
+	return e.item, true
+}
+
+// Ascend calls the iterator for every item in the tree, in ascending order,
+// until iterator returns false.  Iterating doesn't use the items.
+func (t *BoundedBTree[T]) Ascend(iterator ItemIterator[T]) {
+	t.tree.Ascend(func(e *boundedEntry[T]) bool {
+		return iterator(e.item)
+	})
+}
+
+// Descend calls the iterator for every item in the tree, in descending order,
+// until iterator returns false.  Iterating doesn't use the items.
+func (t *BoundedBTree[T]) Descend(iterator ItemIterator[T]) {
+	t.tree.Descend(func(e *boundedEntry[T]) bool {
+		return iterator(e.item)
+	})
+}
+
+// Len returns the number of items currently in the tree.
+func (t *BoundedBTree[T]) Len() int {
+	return t.tree.Len()
+}
+
+// Cap returns the maximum number of items of the tree.
+func (t *BoundedBTree[T]) Cap() int {
+	return t.capacity
+}
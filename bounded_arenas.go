@@ -0,0 +1,12 @@
+//go:build goexperiment.arenas
+
+package btree
+
+import "arena"
+
+func (e *boundedEntry[T]) DeepCopyWithArena(a *arena.Arena) *boundedEntry[T] {
+	e2 := arena.New[boundedEntry[T]](a)
+	e2.item = e.item.DeepCopyWithArena(a)
+
+	return e2
+}
@@ -0,0 +1,132 @@
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestBoundedBTree(t *testing.T) {
+	for _, policy := range []EvictionPolicy{EvictMin, EvictMax, EvictLRU} {
+		var evicted []int
+		tr := NewBounded[*testInt](*btreeDegree, 50, policy, func(item *testInt) {
+			evicted = append(evicted, int(*item))
+		})
+		// recent is the reference model, in recency order.
+		var recent []int
+		use := func(v int) {
+			for i, r := range recent {
+				if r == v {
+					recent = append(recent[:i], recent[i+1:]...)
+					break
+				}
+			}
+			recent = append(recent, v)
+		}
+
+		for i := 0; i < 5000; i++ {
+			v := rand.Intn(200)
+			switch rand.Intn(4) {
+			case 0, 1:
+				evicted = evicted[:0]
+				before := len(recent)
+				_, replaced := tr.ReplaceOrInsert(newTestInt(v))
+				use(v)
+				if replaced != (len(recent) == before) {
+					t.Fatalf("%v: replace of %v returned %v", policy, v, replaced)
+				}
+				if len(recent) > 50 {
+					sorted := append([]int(nil), recent...)
+					sort.Ints(sorted)
+					var want int
+					switch policy {
+					case EvictMin:
+						want = sorted[0]
+					case EvictMax:
+						want = sorted[len(sorted)-1]
+					case EvictLRU:
+						want = recent[0]
+					}
+					if !reflect.DeepEqual(evicted, []int{want}) {
+						t.Fatalf("%v: evicted %v, want [%v]", policy, evicted, want)
+					}
+					for i, r := range recent {
+						if r == want {
+							recent = append(recent[:i], recent[i+1:]...)
+							break
+						}
+					}
+				}
+			case 2:
+				_, ok := tr.Get(newTestInt(v))
+				for _, r := range recent {
+					if r == v {
+						use(v)
+						if !ok {
+							t.Fatalf("%v: %v not found", policy, v)
+						}
+						break
+					}
+				}
+			case 3:
+				if _, ok := tr.Delete(newTestInt(v)); ok {
+					for i, r := range recent {
+						if r == v {
+							recent = append(recent[:i], recent[i+1:]...)
+							break
+						}
+					}
+				}
+			}
+
+			if tr.Len() != len(recent) {
+				t.Fatalf("%v: len %v, want %v", policy, tr.Len(), len(recent))
+			}
+		}
+
+		want := append([]int(nil), recent...)
+		sort.Ints(want)
+		var got []int
+		tr.Ascend(func(item *testInt) bool {
+			got = append(got, int(*item))
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: got %v, want %v", policy, got, want)
+		}
+	}
+}
+
+// TestBoundedConcurrentReads is meant to be run with -race.
+func TestBoundedConcurrentReads(t *testing.T) {
+	tr := NewBounded[*testInt](2, 100, EvictMin, nil)
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(newTestInt(i))
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := newTestInt(i % 200)
+				if _, ok := tr.Get(k); ok != (i%200 < 100) || tr.Has(k) != ok {
+					t.Errorf("get %v: got %v", *k, ok)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkBoundedLRU(b *testing.B) {
+	tr := NewBounded[*testInt](*btreeDegree, 1000, EvictLRU, nil)
+	insertP := rand.Perm(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.ReplaceOrInsert(newTestInt(insertP[i%len(insertP)]))
+	}
+}
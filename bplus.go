@@ -0,0 +1,495 @@
+package btree
+
+// bplusNode is a node of a BPlusTree.  The items of a leaf are the items of
+// the tree, and leaves are linked to their neighbours.  The items of an inner
+// node are separators: every item under children[i] is lower than items[i],
+// and every item under children[i+1] is greater than or equal to it.
+type bplusNode[T Item[T]] struct {
+	items      items[T]
+	children   []*bplusNode[T]
+	prev, next *bplusNode[T]
+}
+
+func (n *bplusNode[T]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// child returns the index of the child of an inner node under which the
+// given item belongs.
+func (n *bplusNode[T]) child(item T) int {
+	i, found := n.items.find(item)
+	if found {
+		i++
+	}
+	return i
+}
+
+func (n *bplusNode[T]) insertChildAt(i int, child *bplusNode[T]) {
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+}
+
+func (n *bplusNode[T]) removeChildAt(i int) *bplusNode[T] {
+	child := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	return child
+}
+
+// split splits the node in two halves, and returns the separator of the
+// second half along with it.
+func (n *bplusNode[T]) split() (T, *bplusNode[T]) {
+	i := len(n.items) / 2
+	next := &bplusNode[T]{}
+	if n.leaf() {
+		next.items = append(next.items, n.items[i:]...)
+		n.items.truncate(i)
+		next.prev, next.next = n, n.next
+		if n.next != nil {
+			n.next.prev = next
+		}
+		n.next = next
+		return next.items[0], next
+	}
+	item := n.items[i]
+	next.items = append(next.items, n.items[i+1:]...)
+	n.items.truncate(i)
+	next.children = append(next.children, n.children[i+1:]...)
+	for j := i + 1; j < len(n.children); j++ {
+		n.children[j] = nil
+	}
+	n.children = n.children[:i+1]
+	return item, next
+}
+
+func (n *bplusNode[T]) insert(item T, maxItems int) (_ T, _ bool) {
+	if n.leaf() {
+		i, found := n.items.find(item)
+		if found {
+			out := n.items[i]
+			n.items[i] = item
+			return out, true
+		}
+		n.items.insertAt(i, item)
+		return
+	}
+	i := n.child(item)
+	out, outb := n.children[i].insert(item, maxItems)
+	if len(n.children[i].items) > maxItems {
+		sep, next := n.children[i].split()
+		n.items.insertAt(i, sep)
+		n.insertChildAt(i+1, next)
+	}
+	return out, outb
+}
+
+func (n *bplusNode[T]) remove(item T, minItems int) (_ T, _ bool) {
+	if n.leaf() {
+		i, found := n.items.find(item)
+		if !found {
+			return
+		}
+		return n.items.removeAt(i), true
+	}
+	i := n.child(item)
+	out, outb := n.children[i].remove(item, minItems)
+	if len(n.children[i].items) < minItems {
+		n.rebalance(i, minItems)
+	}
+	return out, outb
+}
+
+// rebalance gives back to child i, which has less than minItems items, an
+// item stolen from a sibling, or merges it with a sibling.
+func (n *bplusNode[T]) rebalance(i, minItems int) {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].items) > minItems:
+		// Steal from left child.
+		stealFrom := n.children[i-1]
+		if child.leaf() {
+			child.items.insertAt(0, stealFrom.items.pop())
+			n.items[i-1] = child.items[0]
+		} else {
+			child.items.insertAt(0, n.items[i-1])
+			n.items[i-1] = stealFrom.items.pop()
+			child.insertChildAt(0, stealFrom.removeChildAt(len(stealFrom.children)-1))
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > minItems:
+		// Steal from right child.
+		stealFrom := n.children[i+1]
+		if child.leaf() {
+			child.items = append(child.items, stealFrom.items.removeAt(0))
+			n.items[i] = stealFrom.items[0]
+		} else {
+			child.items = append(child.items, n.items[i])
+			n.items[i] = stealFrom.items.removeAt(0)
+			child.children = append(child.children, stealFrom.removeChildAt(0))
+		}
+	default:
+		// Merge with right child.
+		if i >= len(n.items) {
+			i--
+			child = n.children[i]
+		}
+		sep := n.items.removeAt(i)
+		merge := n.removeChildAt(i + 1)
+		if child.leaf() {
+			child.next = merge.next
+			if merge.next != nil {
+				merge.next.prev = child
+			}
+		} else {
+			child.items = append(child.items, sep)
+			child.children = append(child.children, merge.children...)
+		}
+		child.items = append(child.items, merge.items...)
+	}
+}
+
+// BPlusTree is a B+tree: its items are only stored in its leaves, which are
+// linked to one another in both directions, while its inner nodes only hold
+// separators to route searches.
+//
+// Once the first item of a range is found, iterating over the range only
+// follows the links between leaves, without going back up the tree and
+// without comparing items other than to the end of the range.  This makes
+// range scans and cursors cheaper than with a BTree, at the cost of storing
+// separators in addition to items.
+//
+// BPlusTree is not safe for concurrent use, and it doesn't support
+// FreeLists, copies, nor the extensions of BTree.
+type BPlusTree[T Item[T]] struct {
+	degree int
+	length int
+	root   *bplusNode[T]
+}
+
+// NewBPlus creates a new B+tree with the given degree: each node holds from
+// degree-1 to 2*degree-1 items, or children for inner nodes.
+func NewBPlus[T Item[T]](degree int) *BPlusTree[T] {
+	if degree <= 1 {
+		panic("bad degree")
+	}
+	return &BPlusTree[T]{degree: degree}
+}
+
+func (t *BPlusTree[T]) maxItems() int {
+	return t.degree*2 - 1
+}
+
+func (t *BPlusTree[T]) minItems() int {
+	return t.degree - 1
+}
+
+// ReplaceOrInsert adds the given item to the tree.  If an item in the tree
+// already equals the given one, it is removed from the tree and returned,
+// and the second return value is true.  Otherwise, (zeroValue, false)
+func (t *BPlusTree[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
+	if t.root == nil {
+		t.root = &bplusNode[T]{}
+	}
+	out, outb := t.root.insert(item, t.maxItems())
+	if len(t.root.items) > t.maxItems() {
+		sep, next := t.root.split()
+		t.root = &bplusNode[T]{
+			items:    items[T]{sep},
+			children: []*bplusNode[T]{t.root, next},
+		}
+	}
+	if !outb {
+		t.length++
+	}
+	return out, outb
+}
+
+// Delete removes an item equal to the passed in item from the tree, returning
+// it.  If no such item exists, returns (zeroValue, false).
+func (t *BPlusTree[T]) Delete(item T) (_ T, _ bool) {
+	if t.root == nil {
+		return
+	}
+	out, outb := t.root.remove(item, t.minItems())
+	if len(t.root.items) == 0 && !t.root.leaf() {
+		t.root = t.root.children[0]
+	}
+	if outb {
+		t.length--
+	}
+	return out, outb
+}
+
+// DeleteMin removes the smallest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *BPlusTree[T]) DeleteMin() (_ T, _ bool) {
+	if min, ok := t.Min(); ok {
+		return t.Delete(min)
+	}
+	return
+}
+
+// DeleteMax removes the largest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *BPlusTree[T]) DeleteMax() (_ T, _ bool) {
+	if max, ok := t.Max(); ok {
+		return t.Delete(max)
+	}
+	return
+}
+
+// leaf returns the leaf under which the given item belongs.
+func (t *BPlusTree[T]) leaf(item T) *bplusNode[T] {
+	n := t.root
+	for n != nil && !n.leaf() {
+		n = n.children[n.child(item)]
+	}
+	return n
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item.
+func (t *BPlusTree[T]) Get(key T) (_ T, _ bool) {
+	n := t.leaf(key)
+	if n == nil {
+		return
+	}
+	if i, found := n.items.find(key); found {
+		return n.items[i], true
+	}
+	return
+}
+
+// Has returns true if the given key is in the tree.
+func (t *BPlusTree[T]) Has(key T) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the
+// tree is empty.
+func (t *BPlusTree[T]) Min() (_ T, _ bool) {
+	c := t.Cursor()
+	if !c.First() {
+		return
+	}
+	return c.Item(), true
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the tree
+// is empty.
+func (t *BPlusTree[T]) Max() (_ T, _ bool) {
+	c := t.Cursor()
+	if !c.Last() {
+		return
+	}
+	return c.Item(), true
+}
+
+// Len returns the number of items currently in the tree.
+func (t *BPlusTree[T]) Len() int {
+	return t.length
+}
+
+// ascend calls the iterator for every item from the cursor up to stop, until
+// iterator returns false.  It walks over the leaves rather than moving the
+// cursor.
+func (t *BPlusTree[T]) ascend(c *BPlusCursor[T], ok bool, stop optionalItem[T], iterator ItemIterator[T]) {
+	if !ok {
+		return
+	}
+	for n, i := c.leaf, c.i; n != nil; n, i = n.next, 0 {
+		for _, item := range n.items[i:] {
+			if stop.valid && !item.Less(stop.item) || !iterator(item) {
+				return
+			}
+		}
+	}
+}
+
+// descend calls the iterator for every item from the cursor down to stop,
+// until iterator returns false.  It walks over the leaves rather than moving
+// the cursor.
+func (t *BPlusTree[T]) descend(c *BPlusCursor[T], ok bool, stop optionalItem[T], iterator ItemIterator[T]) {
+	if !ok {
+		return
+	}
+	for n, i := c.leaf, c.i; n != nil; n = n.prev {
+		for ; i >= 0; i-- {
+			item := n.items[i]
+			if stop.valid && !stop.item.Less(item) || !iterator(item) {
+				return
+			}
+		}
+		if n.prev != nil {
+			i = len(n.prev.items) - 1
+		}
+	}
+}
+
+// AscendRange calls the iterator for every value in the tree within the range
+// [greaterOrEqual, lessThan), until iterator returns false.
+func (t *BPlusTree[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.ascend(c, c.Seek(greaterOrEqual), optional(lessThan), iterator)
+}
+
+// AscendLessThan calls the iterator for every value in the tree within the range
+// [first, pivot), until iterator returns false.
+func (t *BPlusTree[T]) AscendLessThan(pivot T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.ascend(c, c.First(), optional(pivot), iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator for every value in the tree within
+// the range [pivot, last], until iterator returns false.
+func (t *BPlusTree[T]) AscendGreaterOrEqual(pivot T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.ascend(c, c.Seek(pivot), empty[T](), iterator)
+}
+
+// Ascend calls the iterator for every value in the tree within the range
+// [first, last], until iterator returns false.
+func (t *BPlusTree[T]) Ascend(iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.ascend(c, c.First(), empty[T](), iterator)
+}
+
+// DescendRange calls the iterator for every value in the tree within the range
+// [lessOrEqual, greaterThan), until iterator returns false.
+func (t *BPlusTree[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.descend(c, c.seekLessOrEqual(lessOrEqual), optional(greaterThan), iterator)
+}
+
+// DescendLessOrEqual calls the iterator for every value in the tree within the range
+// [pivot, first], until iterator returns false.
+func (t *BPlusTree[T]) DescendLessOrEqual(pivot T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.descend(c, c.seekLessOrEqual(pivot), empty[T](), iterator)
+}
+
+// DescendGreaterThan calls the iterator for every value in the tree within
+// the range [last, pivot), until iterator returns false.
+func (t *BPlusTree[T]) DescendGreaterThan(pivot T, iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.descend(c, c.Last(), optional(pivot), iterator)
+}
+
+// Descend calls the iterator for every value in the tree within the range
+// [last, first], until iterator returns false.
+func (t *BPlusTree[T]) Descend(iterator ItemIterator[T]) {
+	c := t.Cursor()
+	t.descend(c, c.Last(), empty[T](), iterator)
+}
+
+// BPlusCursor walks over the items of a BPlusTree in either direction.  A
+// cursor is positioned by First, Last or Seek, then moved by Next and Prev,
+// which all return whether the cursor is on an item.
+//
+// Modifying the tree invalidates its cursors, which must be positioned again
+// before being used.
+type BPlusCursor[T Item[T]] struct {
+	t    *BPlusTree[T]
+	leaf *bplusNode[T]
+	i    int
+}
+
+// Cursor returns a new cursor over the tree, which isn't positioned on any
+// item.
+func (t *BPlusTree[T]) Cursor() *BPlusCursor[T] {
+	return &BPlusCursor[T]{t: t}
+}
+
+// Valid returns whether the cursor is on an item.
+func (c *BPlusCursor[T]) Valid() bool {
+	return c.leaf != nil
+}
+
+// Item returns the item the cursor is on.  It panics if the cursor isn't
+// Valid.
+func (c *BPlusCursor[T]) Item() T {
+	return c.leaf.items[c.i]
+}
+
+// First moves the cursor to the smallest item in the tree.
+func (c *BPlusCursor[T]) First() bool {
+	n := c.t.root
+	for n != nil && !n.leaf() {
+		n = n.children[0]
+	}
+	c.leaf, c.i = n, 0
+	return c.settle()
+}
+
+// Last moves the cursor to the largest item in the tree.
+func (c *BPlusCursor[T]) Last() bool {
+	n := c.t.root
+	for n != nil && !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	if n == nil || len(n.items) == 0 {
+		c.leaf = nil
+		return false
+	}
+	c.leaf, c.i = n, len(n.items)-1
+	return true
+}
+
+// Seek moves the cursor to the smallest item greater than or equal to key.
+func (c *BPlusCursor[T]) Seek(key T) bool {
+	c.leaf = c.t.leaf(key)
+	if c.leaf != nil {
+		c.i, _ = c.leaf.items.find(key)
+	}
+	return c.settle()
+}
+
+// seekLessOrEqual moves the cursor to the largest item lower than or equal
+// to key.
+func (c *BPlusCursor[T]) seekLessOrEqual(key T) bool {
+	if !c.Seek(key) {
+		return c.Last()
+	}
+	if key.Less(c.Item()) {
+		return c.Prev()
+	}
+	return true
+}
+
+// Next moves the cursor to the next item.
+func (c *BPlusCursor[T]) Next() bool {
+	if c.leaf == nil {
+		return false
+	}
+	c.i++
+	return c.settle()
+}
+
+// Prev moves the cursor to the previous item.
+func (c *BPlusCursor[T]) Prev() bool {
+	if c.leaf == nil {
+		return false
+	}
+	if c.i--; c.i < 0 {
+		c.leaf = c.leaf.prev
+		if c.leaf != nil {
+			c.i = len(c.leaf.items) - 1
+		}
+	}
+	return c.leaf != nil
+}
+
+// settle moves the cursor from the end of its leaf to the next one, and
+// invalidates it if it ran past the last item.  Only the leaf of an empty tree
+// is empty.
+func (c *BPlusCursor[T]) settle() bool {
+	if c.leaf != nil && c.i >= len(c.leaf.items) {
+		c.leaf, c.i = c.leaf.next, 0
+	}
+	if c.leaf != nil && len(c.leaf.items) == 0 {
+		c.leaf = nil
+	}
+	return c.leaf != nil
+}
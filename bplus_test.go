@@ -0,0 +1,303 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checkBPlus checks the node sizes, the separators and the links between the
+// leaves of the tree.
+func checkBPlus(t *testing.T, tr *BPlusTree[*testInt]) {
+	t.Helper()
+	var leaves []*bplusNode[*testInt]
+	var check func(n *bplusNode[*testInt], lo, hi *testInt) int
+	check = func(n *bplusNode[*testInt], lo, hi *testInt) int {
+		if n != tr.root && len(n.items) < tr.minItems() || len(n.items) > tr.maxItems() {
+			t.Fatalf("node has %v items", len(n.items))
+		}
+		for i, item := range n.items {
+			if lo != nil && item.Less(lo) || hi != nil && !item.Less(hi) {
+				t.Fatalf("item %v out of [%v, %v)", item, lo, hi)
+			}
+			if i > 0 && !n.items[i-1].Less(item) {
+				t.Fatalf("items %v and %v out of order", n.items[i-1], item)
+			}
+		}
+		if n.leaf() {
+			leaves = append(leaves, n)
+			return 1
+		}
+		if len(n.children) != len(n.items)+1 {
+			t.Fatalf("node has %v items and %v children", len(n.items), len(n.children))
+		}
+		height := 0
+		for i, c := range n.children {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = n.items[i-1]
+			}
+			if i < len(n.items) {
+				chi = n.items[i]
+			}
+			if h := check(c, clo, chi); i > 0 && h != height {
+				t.Fatalf("children have heights %v and %v", height, h)
+			} else {
+				height = h
+			}
+		}
+		return height + 1
+	}
+	if tr.root == nil {
+		return
+	}
+	check(tr.root, nil, nil)
+	for i, l := range leaves {
+		var prev, next *bplusNode[*testInt]
+		if i > 0 {
+			prev = leaves[i-1]
+		}
+		if i < len(leaves)-1 {
+			next = leaves[i+1]
+		}
+		if l.prev != prev || l.next != next {
+			t.Fatalf("leaf %v is badly linked", i)
+		}
+	}
+}
+
+func TestBPlusTree(t *testing.T) {
+	for _, degree := range []int{2, 3, 16} {
+		tr := NewBPlus[*testInt](degree)
+		model := map[int]bool{}
+		for i := 0; i < 20000; i++ {
+			v := rand.Intn(2000)
+			switch rand.Intn(5) {
+			case 0, 1:
+				_, ok := tr.ReplaceOrInsert(newTestInt(v))
+				if ok != model[v] {
+					t.Fatalf("degree %v: insert %v: got %v", degree, v, ok)
+				}
+				model[v] = true
+			case 2:
+				_, ok := tr.Delete(newTestInt(v))
+				if ok != model[v] {
+					t.Fatalf("degree %v: delete %v: got %v", degree, v, ok)
+				}
+				delete(model, v)
+			case 3:
+				if min, ok := tr.DeleteMin(); ok {
+					delete(model, int(*min))
+				}
+			case 4:
+				if _, ok := tr.Get(newTestInt(v)); ok != model[v] {
+					t.Fatalf("degree %v: get %v: got %v", degree, v, ok)
+				}
+			}
+			if tr.Len() != len(model) {
+				t.Fatalf("degree %v: len %v, want %v", degree, tr.Len(), len(model))
+			}
+			if i%500 == 0 {
+				checkBPlus(t, tr)
+			}
+		}
+		checkBPlus(t, tr)
+
+		var all []int
+		for v := range model {
+			all = append(all, v)
+		}
+		sort.Ints(all)
+		collect := func(each func(ItemIterator[*testInt])) (out []int) {
+			each(func(i *testInt) bool {
+				out = append(out, int(*i))
+				return true
+			})
+			return out
+		}
+		filter := func(keep func(v int) bool, reverse bool) (out []int) {
+			for _, v := range all {
+				if keep(v) {
+					out = append(out, v)
+				}
+			}
+			if reverse {
+				for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+					out[i], out[j] = out[j], out[i]
+				}
+			}
+			return out
+		}
+
+		if got, want := collect(tr.Ascend), filter(func(int) bool { return true }, false); !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %v: ascend\n got: %v\nwant: %v", degree, got, want)
+		}
+		if got, want := collect(tr.Descend), filter(func(int) bool { return true }, true); !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %v: descend\n got: %v\nwant: %v", degree, got, want)
+		}
+		for i := 0; i < 100; i++ {
+			lo, hi := rand.Intn(2100)-50, rand.Intn(2100)-50
+			for _, c := range []struct {
+				name string
+				each func(ItemIterator[*testInt])
+				want []int
+			}{
+				{
+					"ascend range",
+					func(it ItemIterator[*testInt]) { tr.AscendRange(newTestInt(lo), newTestInt(hi), it) },
+					filter(func(v int) bool { return v >= lo && v < hi }, false),
+				},
+				{
+					"ascend less than",
+					func(it ItemIterator[*testInt]) { tr.AscendLessThan(newTestInt(hi), it) },
+					filter(func(v int) bool { return v < hi }, false),
+				},
+				{
+					"ascend greater or equal",
+					func(it ItemIterator[*testInt]) { tr.AscendGreaterOrEqual(newTestInt(lo), it) },
+					filter(func(v int) bool { return v >= lo }, false),
+				},
+				{
+					"descend range",
+					func(it ItemIterator[*testInt]) { tr.DescendRange(newTestInt(hi), newTestInt(lo), it) },
+					filter(func(v int) bool { return v <= hi && v > lo }, true),
+				},
+				{
+					"descend less or equal",
+					func(it ItemIterator[*testInt]) { tr.DescendLessOrEqual(newTestInt(hi), it) },
+					filter(func(v int) bool { return v <= hi }, true),
+				},
+				{
+					"descend greater than",
+					func(it ItemIterator[*testInt]) { tr.DescendGreaterThan(newTestInt(lo), it) },
+					filter(func(v int) bool { return v > lo }, true),
+				},
+			} {
+				if got := collect(c.each); !reflect.DeepEqual(got, c.want) {
+					t.Fatalf("degree %v: %v [%v, %v]\n got: %v\nwant: %v", degree, c.name, lo, hi, got, c.want)
+				}
+			}
+		}
+
+		for tr.Len() > 0 {
+			tr.DeleteMax()
+		}
+		checkBPlus(t, tr)
+		if _, ok := tr.Min(); ok {
+			t.Fatalf("degree %v: empty tree has a min", degree)
+		}
+	}
+}
+
+func TestBPlusCursor(t *testing.T) {
+	tr := NewBPlus[*testInt](2)
+	c := tr.Cursor()
+	if c.First() || c.Last() || c.Seek(newTestInt(0)) || c.Next() || c.Prev() || c.Valid() {
+		t.Fatal("cursor over an empty tree is valid")
+	}
+
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(newTestInt(i * 2))
+	}
+	if !c.Seek(newTestInt(41)) || *c.Item() != 42 {
+		t.Fatalf("seek 41: got %v", *c.Item())
+	}
+	if !c.Seek(newTestInt(42)) || *c.Item() != 42 {
+		t.Fatalf("seek 42: got %v", *c.Item())
+	}
+	if c.Seek(newTestInt(199)) {
+		t.Fatalf("seek past the end: got %v", *c.Item())
+	}
+
+	var got []int
+	for ok := c.Seek(newTestInt(-1)); ok; ok = c.Next() {
+		got = append(got, int(*c.Item()))
+	}
+	if len(got) != 100 || got[0] != 0 || got[99] != 198 {
+		t.Fatalf("forward scan: got %v", got)
+	}
+	if c.Valid() || c.Prev() {
+		t.Fatal("cursor is still valid past the end")
+	}
+
+	got = got[:0]
+	for ok := c.Last(); ok; ok = c.Prev() {
+		got = append(got, int(*c.Item()))
+		if len(got) == 50 {
+			// Turn around halfway.
+			break
+		}
+	}
+	for ok := c.Next(); ok; ok = c.Next() {
+		got = append(got, int(*c.Item()))
+	}
+	if len(got) != 99 || got[49] != 100 || got[50] != 102 || got[98] != 198 {
+		t.Fatalf("backward and forward scan: got %v", got)
+	}
+}
+
+func BenchmarkAscendRangeBPlus(b *testing.B) {
+	arr := rand.Perm(benchmarkTreeSize)
+	tr := NewBPlus[*testInt](*btreeDegree)
+	for _, v := range arr {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	sort.Ints(arr)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := 100
+		tr.AscendRange(newTestInt(100), newTestInt(arr[len(arr)-100]), func(item *testInt) bool {
+			if int(*item) != arr[j] {
+				b.Fatalf("mismatch: expected: %v, got %v", arr[j], item)
+			}
+			j++
+			return true
+		})
+		if j != len(arr)-100 {
+			b.Fatalf("expected: %v, got %v", len(arr)-100, j)
+		}
+	}
+}
+
+func BenchmarkDescendRangeBPlus(b *testing.B) {
+	arr := rand.Perm(benchmarkTreeSize)
+	tr := NewBPlus[*testInt](*btreeDegree)
+	for _, v := range arr {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	sort.Ints(arr)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := len(arr) - 100
+		tr.DescendRange(newTestInt(arr[len(arr)-100]), newTestInt(100), func(item *testInt) bool {
+			if int(*item) != arr[j] {
+				b.Fatalf("mismatch: expected: %v, got %v", arr[j], item)
+			}
+			j--
+			return true
+		})
+		if j != 100 {
+			b.Fatalf("expected: %v, got %v", len(arr)-100, j)
+		}
+	}
+}
+
+func BenchmarkInsertBPlus(b *testing.B) {
+	b.StopTimer()
+	insertP := rand.Perm(benchmarkTreeSize)
+	b.StartTimer()
+	i := 0
+	for i < b.N {
+		tr := NewBPlus[*testInt](*btreeDegree)
+		for _, item := range insertP {
+			tr.ReplaceOrInsert(newTestInt(item))
+			i++
+			if i >= b.N {
+				return
+			}
+		}
+	}
+}
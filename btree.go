@@ -15,7 +15,8 @@
 // Package btree implements in-memory B-Trees of arbitrary degree.
 //
 // btree implements an in-memory B-Tree for use as an ordered data structure.
-// It is not meant for persistent storage solutions.
+// It is not meant for persistent storage solutions, although the durable
+// subpackage can log the modifications of a tree to survive restarts.
 //
 // It has a flatter structure than an equivalent red-black or other binary tree,
 // which in some cases yields better memory usage and/or performance.
@@ -56,6 +57,7 @@
 package btree
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sort"
@@ -129,9 +131,21 @@ func NewWithFreeList[T Item[T]](degree int, f *FreeList[T]) *BTree[T] {
 	return &BTree[T]{
 		degree:   degree,
 		freelist: f,
+		cmp:      isComparable[T](),
 	}
 }
 
+// minSortedRun is the average length under which the ascending runs of a
+// ReplaceOrInsertMany batch are considered too short to be inserted as is.
+const minSortedRun = 4
+
+// byLess sorts a slice of items according to their Less method.
+type byLess[T Item[T]] []T
+
+func (s byLess[T]) Len() int           { return len(s) }
+func (s byLess[T]) Less(i, j int) bool { return s[i].Less(s[j]) }
+func (s byLess[T]) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 // items stores items in a node.
 type items[T Item[T]] []T
 
@@ -201,6 +215,22 @@ func (s items[T]) DeepCopy() items[T] {
 	return s2
 }
 
+// copyFunc returns a copy of the list where each item is replaced by fn(item),
+// or a plain copy of the list if fn is nil.
+func (s items[T]) copyFunc(fn func(T) T) items[T] {
+	s2 := make(items[T], len(s), cap(s))
+	if fn == nil {
+		copy(s2, s)
+		return s2
+	}
+
+	for i, item := range s {
+		s2[i] = fn(item)
+	}
+
+	return s2
+}
+
 // node is an internal node in a tree.
 //
 // It must at all times maintain the invariant that either
@@ -210,6 +240,7 @@ type node[T Item[T]] struct {
 	items    items[T]
 	children items[*node[T]]
 	t        *BTree[T]
+	summary  any // maintained by the tree's augmenter, if any
 }
 
 func (n *node[T]) Less(*node[T]) bool {
@@ -233,6 +264,24 @@ func (n *node[T]) DeepCopy() *node[T] {
 	return n2
 }
 
+// copyFunc returns a copy of the subtree rooted at this node whose nodes
+// belong to t, see items.copyFunc.
+func (n *node[T]) copyFunc(t *BTree[T], fn func(T) T) *node[T] {
+	n2 := &node[T]{t: t}
+
+	if n.items != nil {
+		n2.items = n.items.copyFunc(fn)
+	}
+	if n.children != nil {
+		n2.children = make(items[*node[T]], len(n.children), cap(n.children))
+		for i, child := range n.children {
+			n2.children[i] = child.copyFunc(t, fn)
+		}
+	}
+
+	return n2
+}
+
 // split splits the given node at the given index.  The current node shrinks,
 // and this function returns the item that existed at that index and a new node
 // containing all items/children after it.
@@ -245,9 +294,20 @@ func (n *node[T]) split(i int) (T, *node[T]) {
 		next.children = append(next.children, n.children[i+1:]...)
 		n.children.truncate(i + 1)
 	}
+	n.augment()
+	next.augment()
 	return item, next
 }
 
+// augment updates the summary of the subtree rooted at this node, when its
+// tree is augmented.  It must be called whenever the node's items or
+// children change, once its children's summaries are up to date.
+func (n *node[T]) augment() {
+	if n.t.aug != nil {
+		n.t.aug.update(n)
+	}
+}
+
 // maybeSplitChild checks if a child should be split, and if so splits it.
 // Returns whether or not a split occurred.
 func (n *node[T]) maybeSplitChild(i, maxItems int) bool {
@@ -265,35 +325,39 @@ func (n *node[T]) maybeSplitChild(i, maxItems int) bool {
 // no nodes in the subtree exceed maxItems items.  Should an equivalent item be
 // be found/replaced by insert, it will be returned.
 func (n *node[T]) insert(item T, maxItems int) (_ T, _ bool) {
-	i, found := n.items.find(item)
+	i, found := n.find(item)
 	if found {
 		out := n.items[i]
 		n.items[i] = item
+		n.augment()
 		return out, true
 	}
 	if len(n.children) == 0 {
 		n.items.insertAt(i, item)
+		n.augment()
 		return
 	}
 	if n.maybeSplitChild(i, maxItems) {
-		inTree := n.items[i]
-		switch {
-		case item.Less(inTree):
+		switch n.t.compare(item, n.items[i]) {
+		case -1:
 			// no change, we want first split node
-		case inTree.Less(item):
+		case 1:
 			i++ // we want second split node
 		default:
 			out := n.items[i]
 			n.items[i] = item
+			n.augment()
 			return out, true
 		}
 	}
-	return n.children[i].insert(item, maxItems)
+	out, outb := n.children[i].insert(item, maxItems)
+	n.augment()
+	return out, outb
 }
 
 // get finds the given key in the subtree and returns it.
 func (n *node[T]) get(key T) (_ T, _ bool) {
-	i, found := n.items.find(key)
+	i, found := n.find(key)
 	if found {
 		return n.items[i], true
 	} else if len(n.children) > 0 {
@@ -302,6 +366,37 @@ func (n *node[T]) get(key T) (_ T, _ bool) {
 	return
 }
 
+// nearest returns the item of the subtree closest to key in the given
+// direction: the greatest item lower than key when descending, the lowest
+// item greater than key when ascending.  key itself is returned if it is in
+// the subtree and inclusive is true.
+func (n *node[T]) nearest(key T, dir Direction, inclusive bool) (_ T, _ bool) {
+	best := empty[T]()
+	for n != nil {
+		i, found := n.find(key)
+		if found && inclusive {
+			return n.items[i], true
+		}
+		next := i
+		switch {
+		case dir == Descending && i > 0:
+			best = optional(n.items[i-1])
+		case dir == Ascending && found:
+			next = i + 1
+			if next < len(n.items) {
+				best = optional(n.items[next])
+			}
+		case dir == Ascending && i < len(n.items):
+			best = optional(n.items[i])
+		}
+		if len(n.children) == 0 {
+			break
+		}
+		n = n.children[next]
+	}
+	return best.item, best.valid
+}
+
 // min returns the first item in the subtree.
 func min[T Item[T]](n *node[T]) (_ T, found bool) {
 	if n == nil {
@@ -346,19 +441,25 @@ func (n *node[T]) remove(item T, minItems int, typ toRemove) (_ T, _ bool) {
 	switch typ {
 	case removeMax:
 		if len(n.children) == 0 {
-			return n.items.pop(), true
+			out := n.items.pop()
+			n.augment()
+			return out, true
 		}
 		i = len(n.items)
 	case removeMin:
 		if len(n.children) == 0 {
-			return n.items.removeAt(0), true
+			out := n.items.removeAt(0)
+			n.augment()
+			return out, true
 		}
 		i = 0
 	case removeItem:
-		i, found = n.items.find(item)
+		i, found = n.find(item)
 		if len(n.children) == 0 {
 			if found {
-				return n.items.removeAt(i), true
+				out := n.items.removeAt(i)
+				n.augment()
+				return out, true
 			}
 			return
 		}
@@ -382,11 +483,16 @@ func (n *node[T]) remove(item T, minItems int, typ toRemove) (_ T, _ bool) {
 		// and set it into where we pulled the item from.
 		var zero T
 		n.items[i], _ = child.remove(zero, minItems, removeMax)
+		n.augment()
 		return out, true
 	}
 	// Final recursive call.  Once we're here, we know that the item isn't in this
 	// node and that the child is big enough to remove from.
-	return child.remove(item, minItems, typ)
+	out, outb := child.remove(item, minItems, typ)
+	if outb {
+		n.augment()
+	}
+	return out, outb
 }
 
 // growChildAndRemove grows child 'i' to make sure it's possible to remove an
@@ -424,6 +530,8 @@ func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove)
 		if len(stealFrom.children) > 0 {
 			child.children.insertAt(0, stealFrom.children.pop())
 		}
+		stealFrom.augment()
+		child.augment()
 	} else if i < len(n.items) && len(n.children[i+1].items) > minItems {
 		// steal from right child
 		child := n.children[i]
@@ -434,6 +542,8 @@ func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove)
 		if len(stealFrom.children) > 0 {
 			child.children = append(child.children, stealFrom.children.removeAt(0))
 		}
+		stealFrom.augment()
+		child.augment()
 	} else {
 		if i >= len(n.items) {
 			i--
@@ -446,15 +556,137 @@ func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove)
 		child.items = append(child.items, mergeChild.items...)
 		child.children = append(child.children, mergeChild.children...)
 		n.t.freeNode(mergeChild)
+		child.augment()
 	}
 	return n.remove(item, minItems, typ)
 }
 
-type direction int
+// removeMany removes the items equal to the sorted keys from the subtree
+// rooted at this node and appends them to removed.  Keys equal to an item of
+// an internal node are not handled here: the item is appended to internal
+// and left in place.
+//
+// Rebalancing is deferred until all of a node's children have been
+// processed, see fixChildren.  This node itself may be left with fewer than
+// minItems items, it is up to its parent to fix it.
+func (n *node[T]) removeMany(keys []T, minItems, maxItems int, removed, internal *[]T) {
+	if len(n.children) == 0 {
+		j := 0
+		for _, item := range n.items {
+			c := 1
+			for len(keys) > 0 {
+				if c = n.t.compare(keys[0], item); c >= 0 {
+					break
+				}
+				keys = keys[1:]
+			}
+			if len(keys) > 0 && c == 0 {
+				*removed = append(*removed, item)
+				continue
+			}
+			n.items[j] = item
+			j++
+		}
+		n.items.truncate(j)
+		n.augment()
+		return
+	}
+	for i := 0; i <= len(n.items) && len(keys) > 0; i++ {
+		// Keys lower than items[i] belong to child i.
+		end := len(keys)
+		if i < len(n.items) {
+			end = sort.Search(len(keys), func(j int) bool {
+				return !keys[j].Less(n.items[i])
+			})
+		}
+		if end > 0 {
+			n.children[i].removeMany(keys[:end], minItems, maxItems, removed, internal)
+		}
+		keys = keys[end:]
+		if i < len(n.items) && len(keys) > 0 && !n.items[i].Less(keys[0]) {
+			*internal = append(*internal, n.items[i])
+			for len(keys) > 0 && !n.items[i].Less(keys[0]) {
+				keys = keys[1:]
+			}
+		}
+	}
+	n.fixChildren(minItems, maxItems)
+	n.augment()
+}
+
+// fixChildren rebalances the children of this node which have fewer than
+// minItems items with their siblings.  Once done, all children have at least
+// minItems items, unless this node has been left with a single child.
+func (n *node[T]) fixChildren(minItems, maxItems int) {
+	for i := 0; i < len(n.children) && len(n.children) > 1; {
+		if len(n.children[i].items) >= minItems {
+			i++
+			continue
+		}
+		if i == len(n.children)-1 {
+			i--
+		}
+		if !n.rebalance(i, maxItems, minItems) {
+			i++
+		}
+	}
+}
+
+// rebalance evens out the items of children i and i+1, or merges child i+1
+// into child i if both fit in a single node.  It returns whether the children
+// were merged.
+func (n *node[T]) rebalance(i, maxItems, minItems int) (merged bool) {
+	left, right := n.children[i], n.children[i+1]
+	// A child left without items by removeMany has a single child of its own,
+	// which may itself need to be fixed once it has got siblings.
+	fix := len(left.children) > 0 && (len(left.items) == 0 || len(right.items) == 0)
+	if len(left.items)+len(right.items) < maxItems {
+		left.items = append(left.items, n.items.removeAt(i))
+		left.items = append(left.items, right.items...)
+		left.children = append(left.children, right.children...)
+		n.children.removeAt(i + 1)
+		n.t.freeNode(right)
+		if fix {
+			left.fixChildren(minItems, maxItems)
+		}
+		left.augment()
+		return true
+	}
+
+	all := make(items[T], 0, len(left.items)+len(right.items)+1)
+	all = append(all, left.items...)
+	all = append(all, n.items[i])
+	all = append(all, right.items...)
+	mid := len(all) / 2
+	left.items.truncate(0)
+	left.items = append(left.items, all[:mid]...)
+	n.items[i] = all[mid]
+	right.items.truncate(0)
+	right.items = append(right.items, all[mid+1:]...)
+	if len(left.children) > 0 {
+		children := make(items[*node[T]], 0, len(left.children)+len(right.children))
+		children = append(children, left.children...)
+		children = append(children, right.children...)
+		left.children.truncate(0)
+		left.children = append(left.children, children[:mid+1]...)
+		right.children.truncate(0)
+		right.children = append(right.children, children[mid+1:]...)
+	}
+	if fix {
+		left.fixChildren(minItems, maxItems)
+		right.fixChildren(minItems, maxItems)
+	}
+	left.augment()
+	right.augment()
+	return false
+}
+
+// Direction is the order in which items of the tree are visited.
+type Direction int
 
 const (
-	descend = direction(-1)
-	ascend  = direction(+1)
+	Descending = Direction(-1)
+	Ascending  = Direction(+1)
 )
 
 type optionalItem[T Item[T]] struct {
@@ -469,79 +701,121 @@ func empty[T Item[T]]() optionalItem[T] {
 	return optionalItem[T]{}
 }
 
-// iterate provides a simple method for iterating over elements in the tree.
-//
-// When ascending, the 'start' should be less than 'stop' and when descending,
-// the 'start' should be greater than 'stop'. Setting 'includeStart' to true
-// will force the iterator to include the first item when it equals 'start',
-// thus creating a "greaterOrEqual" or "lessThanEqual" rather than just a
-// "greaterThan" or "lessThan" queries.
-func (n *node[T]) iterate(dir direction, start, stop optionalItem[T], includeStart bool, hit bool, iter ItemIterator[T]) (bool, bool) {
-	var ok, found bool
-	var index int
+// Bound is one end of a range of items, see BTree.Range.
+type Bound[T Item[T]] struct {
+	// Item is the item the range starts or stops at.
+	Item T
+	// Inclusive makes Item part of the range, provided it is in the tree.
+	Inclusive bool
+	// Unbounded makes the range extend to the first or last item of the tree,
+	// Item and Inclusive are then ignored.
+	Unbounded bool
+}
+
+// Unbounded returns a Bound which doesn't limit its end of a range.
+func Unbounded[T Item[T]]() Bound[T] {
+	return Bound[T]{Unbounded: true}
+}
+
+// above returns true if item is within a range whose lower end is b.
+func (t *BTree[T]) above(b Bound[T], item T) bool {
+	switch {
+	case b.Unbounded:
+		return true
+	case t.cmp:
+		c := t.compare(b.Item, item)
+		return c < 0 || b.Inclusive && c == 0
+	}
+	if b.Inclusive {
+		return !item.Less(b.Item)
+	}
+	return b.Item.Less(item)
+}
+
+// below returns true if item is within a range whose upper end is b.
+func (t *BTree[T]) below(b Bound[T], item T) bool {
+	switch {
+	case b.Unbounded:
+		return true
+	case t.cmp:
+		c := t.compare(item, b.Item)
+		return c < 0 || b.Inclusive && c == 0
+	}
+	if b.Inclusive {
+		return !b.Item.Less(item)
+	}
+	return item.Less(b.Item)
+}
+
+// iterate calls iter for every item of the subtree within the range between
+// lo and hi, in the given direction.  It returns false as soon as iter does,
+// or as soon as it gets past the end of the range.
+func (n *node[T]) iterate(dir Direction, lo, hi Bound[T], iter ItemIterator[T]) bool {
+	unbounded := Unbounded[T]()
 	switch dir {
-	case ascend:
-		if start.valid {
-			index, _ = n.items.find(start.item)
+	case Ascending:
+		i, found := 0, false
+		if !lo.Unbounded {
+			i, found = n.find(lo.Item)
 		}
-		for i := index; i < len(n.items); i++ {
-			if len(n.children) > 0 {
-				if hit, ok = n.children[i].iterate(dir, start, stop, includeStart, hit, iter); !ok {
-					return hit, false
-				}
-			}
-			if !includeStart && !hit && start.valid && !start.item.Less(n.items[i]) {
-				hit = true
-				continue
+		if len(n.children) > 0 && !found {
+			if !n.children[i].iterate(dir, lo, hi, iter) {
+				return false
 			}
-			hit = true
-			if stop.valid && !n.items[i].Less(stop.item) {
-				return hit, false
+		}
+		// Every item and child visited from now on is above lo.
+		skip := found && !lo.Inclusive
+		for ; i < len(n.items); i++ {
+			if skip {
+				skip = false
+			} else {
+				if !n.t.below(hi, n.items[i]) {
+					return false
+				}
+				if !iter(n.items[i]) {
+					return false
+				}
 			}
-			if !iter(n.items[i]) {
-				return hit, false
+			if len(n.children) > 0 {
+				if !n.children[i+1].iterate(dir, unbounded, hi, iter) {
+					return false
+				}
 			}
 		}
-		if len(n.children) > 0 {
-			if hit, ok = n.children[len(n.children)-1].iterate(dir, start, stop, includeStart, hit, iter); !ok {
-				return hit, false
-			}
+	case Descending:
+		i, found := len(n.items), false
+		if !hi.Unbounded {
+			i, found = n.find(hi.Item)
 		}
-	case descend:
-		if start.valid {
-			index, found = n.items.find(start.item)
-			if !found {
-				index = index - 1
+		if !found {
+			if len(n.children) > 0 {
+				if !n.children[i].iterate(dir, lo, hi, iter) {
+					return false
+				}
 			}
-		} else {
-			index = len(n.items) - 1
+			i--
 		}
-		for i := index; i >= 0; i-- {
-			if start.valid && !n.items[i].Less(start.item) {
-				if !includeStart || hit || start.item.Less(n.items[i]) {
-					continue
+		// Every item and child visited from now on is below hi.
+		skip := found && !hi.Inclusive
+		for ; i >= 0; i-- {
+			if skip {
+				skip = false
+			} else {
+				if !n.t.above(lo, n.items[i]) {
+					return false
+				}
+				if !iter(n.items[i]) {
+					return false
 				}
 			}
 			if len(n.children) > 0 {
-				if hit, ok = n.children[i+1].iterate(dir, start, stop, includeStart, hit, iter); !ok {
-					return hit, false
+				if !n.children[i].iterate(dir, lo, unbounded, iter) {
+					return false
 				}
 			}
-			if stop.valid && !stop.item.Less(n.items[i]) {
-				return hit, false //	continue
-			}
-			hit = true
-			if !iter(n.items[i]) {
-				return hit, false
-			}
-		}
-		if len(n.children) > 0 {
-			if hit, ok = n.children[0].iterate(dir, start, stop, includeStart, hit, iter); !ok {
-				return hit, false
-			}
 		}
 	}
-	return hit, true
+	return true
 }
 
 // print is used for testing/debugging purposes.
@@ -566,6 +840,12 @@ type BTree[T Item[T]] struct {
 	length   int
 	root     *node[T]
 	freelist *FreeList[T]
+	aug      augmenter[T]
+	cmp      bool // whether T implements Comparable[T]
+	strategy SearchStrategy
+	ordered  *orderedSearch[T] // set by NewOrdered
+
+	observers []*observation[T]
 }
 
 func setBTreeRootRecursive[T Item[T]](t *BTree[T], n *node[T]) {
@@ -578,6 +858,7 @@ func setBTreeRootRecursive[T Item[T]](t *BTree[T], n *node[T]) {
 
 func (t *BTree[T]) DeepCopy() *BTree[T] {
 	t2 := New[T](t.degree)
+	t2.strategy, t2.ordered = t.strategy, t.ordered
 	t2.root = t.root.DeepCopy()
 	t2.length = t.length
 
@@ -586,6 +867,97 @@ func (t *BTree[T]) DeepCopy() *BTree[T] {
 	return t2
 }
 
+// CopyStructure returns a copy of the tree which duplicates its nodes but
+// shares its items with t instead of calling Item.DeepCopy on each of them.
+//
+// This is only safe when items are immutable, since any change made to an
+// item through one of the trees is visible from the other.
+func (t *BTree[T]) CopyStructure() *BTree[T] {
+	return t.CopyFunc(nil)
+}
+
+// CopyFunc returns a copy of the tree in which every item is replaced by
+// fn(item).  A nil fn shares the items between both trees, see CopyStructure.
+//
+// fn must not change the position of an item within the tree's ordering,
+// otherwise the returned tree is corrupted.
+func (t *BTree[T]) CopyFunc(fn func(T) T) *BTree[T] {
+	t2 := New[T](t.degree)
+	t2.strategy, t2.ordered = t.strategy, t.ordered
+	t2.length = t.length
+	if t.root != nil {
+		t2.root = t.root.copyFunc(t2, fn)
+	}
+
+	return t2
+}
+
+// DeepCopyParallel returns a deep copy of the tree like DeepCopy does, but
+// copies the subtrees hanging off the root concurrently using at most workers
+// goroutines.
+//
+// If ctx is done before the copy completes, the remaining subtrees are not
+// copied and DeepCopyParallel returns a nil tree along with ctx.Err().
+func (t *BTree[T]) DeepCopyParallel(ctx context.Context, workers int) (*BTree[T], error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	t2 := New[T](t.degree)
+	t2.strategy, t2.ordered = t.strategy, t.ordered
+	t2.length = t.length
+	if t.root == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return t2, nil
+	}
+
+	deepCopy := func(item T) T {
+		return item.DeepCopy()
+	}
+
+	root := &node[T]{t: t2}
+	if t.root.items != nil {
+		root.items = t.root.items.copyFunc(deepCopy)
+	}
+	if t.root.children != nil {
+		root.children = make(items[*node[T]], len(t.root.children), cap(t.root.children))
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, workers)
+	loop:
+		for i, child := range t.root.children {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break loop
+			}
+
+			wg.Add(1)
+			go func(i int, child *node[T]) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				if ctx.Err() != nil {
+					return
+				}
+				root.children[i] = child.copyFunc(t2, deepCopy)
+			}(i, child)
+		}
+		wg.Wait()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t2.root = root
+
+	return t2, nil
+}
+
 // LessFunc[T] determines how to order a type 'T'.  It should implement a strict
 // ordering, and should return true if within that ordering, 'a' < 'b'.
 type LessFunc[T Item[T]] func(a, b T) bool
@@ -612,9 +984,24 @@ func (t *BTree[T]) freeNode(n *node[T]) {
 	n.items.truncate(0)
 	n.children.truncate(0)
 	n.t = nil // clear to allow GC
+	n.summary = nil
 	t.freelist.freeNode(n)
 }
 
+// maybeSplitRoot splits the root of the tree if it is full, which adds a level
+// to the tree.
+func (t *BTree[T]) maybeSplitRoot() {
+	if len(t.root.items) < t.maxItems() {
+		return
+	}
+	item, second := t.root.split(t.maxItems() / 2)
+	oldroot := t.root
+	t.root = t.newNode()
+	t.root.items = append(t.root.items, item)
+	t.root.children = append(t.root.children, oldroot, second)
+	t.root.augment()
+}
+
 // ReplaceOrInsert adds the given item to the tree.  If an item in the tree
 // already equals the given one, it is removed from the tree and returned,
 // and the second return value is true.  Otherwise, (zeroValue, false)
@@ -624,42 +1011,295 @@ func (t *BTree[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
 	if t.root == nil {
 		t.root = t.newNode()
 		t.root.items = append(t.root.items, item)
+		t.root.augment()
 		t.length++
+		t.notify(Change[T]{Op: OpInsert, New: item})
 		return
-	} else {
-		if len(t.root.items) >= t.maxItems() {
-			item2, second := t.root.split(t.maxItems() / 2)
-			oldroot := t.root
-			t.root = t.newNode()
-			t.root.items = append(t.root.items, item2)
-			t.root.children = append(t.root.children, oldroot, second)
-		}
 	}
+	t.maybeSplitRoot()
 	out, outb := t.root.insert(item, t.maxItems())
 	if !outb {
 		t.length++
+		t.notify(Change[T]{Op: OpInsert, New: item})
+	} else {
+		t.notify(Change[T]{Op: OpReplace, Old: out, New: item})
 	}
 	return out, outb
 }
 
+// ReplaceOrInsertMany adds the given items to the tree as if ReplaceOrInsert
+// was called on each of them in turn, and returns the items which were
+// replaced.
+//
+// Ascending runs of items landing in the same leaf are inserted with a single
+// descent of the tree, which makes this much cheaper than individual calls
+// when batch is sorted or mostly sorted.  A batch made of too many short runs
+// is stable sorted into a copy first, batch itself is never modified.
+func (t *BTree[T]) ReplaceOrInsertMany(batch []T) (replaced []T) {
+	runs := 1
+	for i := 1; i < len(batch); i++ {
+		if batch[i].Less(batch[i-1]) {
+			runs++
+		}
+	}
+	if runs > 1 && runs*minSortedRun > len(batch) {
+		batch = append([]T(nil), batch...)
+		sort.Stable(byLess[T](batch))
+	}
+
+	observed := len(t.observers) > 0
+	var changes []Change[T]
+
+	maxItems := t.maxItems()
+	var path []*node[T]
+	for len(batch) > 0 {
+		if t.root == nil {
+			t.root = t.newNode()
+		} else {
+			t.maybeSplitRoot()
+		}
+
+		// Descend to the leaf where batch[0] belongs, splitting full nodes on the
+		// way down like insert does, and remember the upper bound of that leaf.
+		item := batch[0]
+		n := t.root
+		hi := empty[T]()
+		path = append(path[:0], n)
+		for len(n.children) > 0 {
+			i, found := n.find(item)
+			if !found && n.maybeSplitChild(i, maxItems) {
+				switch t.compare(item, n.items[i]) {
+				case -1:
+					// no change, we want first split node
+				case 1:
+					i++ // we want second split node
+				default:
+					found = true
+				}
+			}
+			if found {
+				if observed {
+					changes = append(changes, Change[T]{Op: OpReplace, Old: n.items[i], New: item})
+				}
+				replaced = append(replaced, n.items[i])
+				n.items[i] = item
+				batch = batch[1:]
+				break
+			}
+			if i < len(n.items) {
+				hi = optional(n.items[i])
+			}
+			n = n.children[i]
+			path = append(path, n)
+		}
+		if len(n.children) > 0 {
+			t.augmentPath(path)
+			continue
+		}
+
+		// Every following item of the run lower than the leaf's upper bound
+		// belongs to this leaf as well, so keep inserting there while there is
+		// room.
+		for pos, first := 0, true; len(batch) > 0; batch, first = batch[1:], false {
+			item := batch[0]
+			if !first && (hi.valid && !item.Less(hi.item) || item.Less(n.items[pos])) {
+				break
+			}
+			i, found := n.t.search(n.items[pos:], item)
+			i += pos
+			if found {
+				if observed {
+					changes = append(changes, Change[T]{Op: OpReplace, Old: n.items[i], New: item})
+				}
+				replaced = append(replaced, n.items[i])
+				n.items[i] = item
+			} else {
+				if len(n.items) >= maxItems {
+					break
+				}
+				if observed {
+					changes = append(changes, Change[T]{Op: OpInsert, New: item})
+				}
+				n.items.insertAt(i, item)
+				t.length++
+			}
+			pos = i
+		}
+		t.augmentPath(path)
+	}
+	t.notifyBatch(changes)
+
+	return replaced
+}
+
+// augmentPath updates the summaries of the nodes along the path from the root
+// to a modified node, bottom up.
+func (t *BTree[T]) augmentPath(path []*node[T]) {
+	if t.aug == nil {
+		return
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].augment()
+	}
+}
+
+// UpsertAction tells Upsert what to do with the item returned by its callback.
+type UpsertAction int
+
+const (
+	UpsertKeep    UpsertAction = iota // leaves the tree unchanged
+	UpsertReplace                     // replaces the existing item, if any
+	UpsertInsert                      // inserts the item, if none exists
+	UpsertDelete                      // removes the existing item, if any
+)
+
+// UpsertFunc is called by Upsert with the item of the tree equal to the key,
+// if exists is true, or with the zero value otherwise.  It returns the item
+// to store and what to do with it.
+type UpsertFunc[T Item[T]] func(old T, exists bool) (T, UpsertAction)
+
+// Upsert looks for the item equal to key and lets fn decide, in the same
+// descent of the tree, whether to keep it, replace it, insert a new item or
+// delete it.  It returns the action which has been applied, which is
+// UpsertKeep when fn asks to replace or delete an item which doesn't exist,
+// or to insert one which already does.
+//
+// The item returned by fn must be equal to key, Upsert panics otherwise since
+// it would change the position of the item in the tree.
+//
+// Deleting an item which lives in an internal node, or in a leaf which can't
+// spare it, goes through a second descent like Delete does.
+func (t *BTree[T]) Upsert(key T, fn UpsertFunc[T]) UpsertAction {
+	if len(t.observers) == 0 {
+		return t.upsert(key, fn)
+	}
+
+	var old, item T
+	var exists bool
+	action := t.upsert(key, func(o T, e bool) (T, UpsertAction) {
+		var action UpsertAction
+		old, exists = o, e
+		item, action = fn(o, e)
+		return item, action
+	})
+	switch {
+	case action == UpsertDelete:
+		t.notify(Change[T]{Op: OpDelete, Old: old})
+	case action == UpsertKeep:
+	case exists:
+		t.notify(Change[T]{Op: OpReplace, Old: old, New: item})
+	default:
+		t.notify(Change[T]{Op: OpInsert, New: item})
+	}
+
+	return action
+}
+
+func (t *BTree[T]) upsert(key T, fn UpsertFunc[T]) UpsertAction {
+	check := func(item T) {
+		if t.compare(key, item) != 0 {
+			panic("btree: Upsert changed the position of the item")
+		}
+	}
+
+	if t.root == nil {
+		var zero T
+		item, action := fn(zero, false)
+		if action != UpsertInsert {
+			return UpsertKeep
+		}
+		check(item)
+		t.root = t.newNode()
+		t.root.items = append(t.root.items, item)
+		t.root.augment()
+		t.length++
+		return action
+	}
+	t.maybeSplitRoot()
+
+	n := t.root
+	var path []*node[T]
+	for {
+		if t.aug != nil {
+			path = append(path, n)
+		}
+		i, found := n.find(key)
+		if !found && len(n.children) > 0 && n.maybeSplitChild(i, t.maxItems()) {
+			switch t.compare(key, n.items[i]) {
+			case -1:
+				// no change, we want first split node
+			case 1:
+				i++ // we want second split node
+			default:
+				found = true
+			}
+		}
+
+		if found {
+			item, action := fn(n.items[i], true)
+			switch action {
+			case UpsertReplace:
+				check(item)
+				n.items[i] = item
+				t.augmentPath(path)
+			case UpsertDelete:
+				if len(n.children) == 0 && (n == t.root || len(n.items) > t.minItems()) {
+					n.items.removeAt(i)
+					t.length--
+					t.augmentPath(path)
+				} else {
+					t.deleteItem(n.items[i], removeItem)
+				}
+			default:
+				return UpsertKeep
+			}
+			return action
+		}
+
+		if len(n.children) == 0 {
+			var zero T
+			item, action := fn(zero, false)
+			if action != UpsertInsert {
+				return UpsertKeep
+			}
+			check(item)
+			n.items.insertAt(i, item)
+			t.length++
+			t.augmentPath(path)
+			return action
+		}
+		n = n.children[i]
+	}
+}
+
 // Delete removes an item equal to the passed in item from the tree, returning
 // it.  If no such item exists, returns (zeroValue, false).
 func (t *BTree[T]) Delete(item T) (T, bool) {
-	return t.deleteItem(item, removeItem)
+	return t.deleteObserved(item, removeItem)
 }
 
 // DeleteMin removes the smallest item in the tree and returns it.
 // If no such item exists, returns (zeroValue, false).
 func (t *BTree[T]) DeleteMin() (T, bool) {
 	var zero T
-	return t.deleteItem(zero, removeMin)
+	return t.deleteObserved(zero, removeMin)
 }
 
 // DeleteMax removes the largest item in the tree and returns it.
 // If no such item exists, returns (zeroValue, false).
 func (t *BTree[T]) DeleteMax() (T, bool) {
 	var zero T
-	return t.deleteItem(zero, removeMax)
+	return t.deleteObserved(zero, removeMax)
+}
+
+// deleteObserved is deleteItem followed by the notification of the tree's
+// observers.
+func (t *BTree[T]) deleteObserved(item T, typ toRemove) (T, bool) {
+	out, ok := t.deleteItem(item, typ)
+	if ok {
+		t.notify(Change[T]{Op: OpDelete, Old: out})
+	}
+	return out, ok
 }
 
 func (t *BTree[T]) deleteItem(item T, typ toRemove) (_ T, _ bool) {
@@ -678,76 +1318,239 @@ func (t *BTree[T]) deleteItem(item T, typ toRemove) (_ T, _ bool) {
 	return out, outb
 }
 
+// DeleteMany removes the items equal to the given keys from the tree and
+// returns them in ascending order.  Keys which aren't in the tree are
+// ignored.
+//
+// Unlike successive calls to Delete, which rebalance the tree on the way down
+// for each item, DeleteMany removes all the items in a single pass over the
+// tree and only rebalances a node once all its children have been processed.
+// Nodes freed by the rebalancing are handed back to the tree's FreeList.
+// keys itself is never modified.
+func (t *BTree[T]) DeleteMany(keys []T) (removed []T) {
+	if t.root == nil || len(keys) == 0 {
+		return nil
+	}
+	if !sort.IsSorted(byLess[T](keys)) {
+		keys = append([]T(nil), keys...)
+		sort.Sort(byLess[T](keys))
+	}
+
+	var internal []T
+	t.root.removeMany(keys, t.minItems(), t.maxItems(), &removed, &internal)
+	t.length -= len(removed)
+	for len(t.root.items) == 0 && len(t.root.children) > 0 {
+		oldroot := t.root
+		t.root = t.root.children[0]
+		t.freeNode(oldroot)
+	}
+
+	if len(internal) == 0 {
+		t.notifyDeleted(removed)
+		return removed
+	}
+
+	// Items held by internal nodes need a predecessor to be pulled up in their
+	// place, which is what the regular removal path does.  Both lists are
+	// sorted, merge them.
+	merged := make([]T, 0, len(removed)+len(internal))
+	for _, item := range internal {
+		out, ok := t.deleteItem(item, removeItem)
+		if !ok {
+			continue
+		}
+		for len(removed) > 0 && removed[0].Less(out) {
+			merged = append(merged, removed[0])
+			removed = removed[1:]
+		}
+		merged = append(merged, out)
+	}
+	merged = append(merged, removed...)
+	t.notifyDeleted(merged)
+
+	return merged
+}
+
+// notifyDeleted notifies the observers of the tree of the deletion of items.
+func (t *BTree[T]) notifyDeleted(items []T) {
+	if len(t.observers) == 0 {
+		return
+	}
+	changes := make([]Change[T], len(items))
+	for i, item := range items {
+		changes[i] = Change[T]{Op: OpDelete, Old: item}
+	}
+	t.notifyBatch(changes)
+}
+
+// RangeQuery is a query over the items of a tree within a range, see
+// BTree.Range.
+type RangeQuery[T Item[T]] struct {
+	t      *BTree[T]
+	lo, hi Bound[T]
+	dir    Direction
+	offset int
+	limit  int
+}
+
+// Range returns a query over the items of the tree between lo and hi, visited
+// in the given direction.  lo is always the lower end of the range and hi the
+// upper one, whatever the direction.
+//
+// For instance, the items within (lo, hi] can be listed from the highest one
+// with:
+//
+//	tr.Range(Bound[T]{Item: lo}, Bound[T]{Item: hi, Inclusive: true}, Descending).Each(iterator)
+func (t *BTree[T]) Range(lo, hi Bound[T], dir Direction) RangeQuery[T] {
+	return RangeQuery[T]{t: t, lo: lo, hi: hi, dir: dir, limit: -1}
+}
+
+// Offset returns a copy of the query which skips the first n items of the
+// range.
+func (q RangeQuery[T]) Offset(n int) RangeQuery[T] {
+	q.offset = n
+	return q
+}
+
+// Limit returns a copy of the query which visits at most n items, or all of
+// them if n is negative.
+func (q RangeQuery[T]) Limit(n int) RangeQuery[T] {
+	q.limit = n
+	return q
+}
+
+// Each calls the iterator for every item within the query, until iterator
+// returns false.
+func (q RangeQuery[T]) Each(iterator ItemIterator[T]) {
+	if q.t.root == nil || q.limit == 0 {
+		return
+	}
+	if q.offset > 0 || q.limit > 0 {
+		offset, limit, inner := q.offset, q.limit, iterator
+		iterator = func(item T) bool {
+			if offset > 0 {
+				offset--
+				return true
+			}
+			limit--
+			return inner(item) && limit != 0
+		}
+	}
+	q.t.root.iterate(q.dir, q.lo, q.hi, iterator)
+}
+
+// Items returns the items within the query.
+func (q RangeQuery[T]) Items() (out []T) {
+	q.Each(func(item T) bool {
+		out = append(out, item)
+		return true
+	})
+	return out
+}
+
 // AscendRange calls the iterator for every value in the tree within the range
 // [greaterOrEqual, lessThan), until iterator returns false.
 func (t *BTree[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(ascend, optional(greaterOrEqual), optional(lessThan), true, false, iterator)
+	t.Range(Bound[T]{Item: greaterOrEqual, Inclusive: true}, Bound[T]{Item: lessThan}, Ascending).Each(iterator)
 }
 
 // AscendLessThan calls the iterator for every value in the tree within the range
 // [first, pivot), until iterator returns false.
 func (t *BTree[T]) AscendLessThan(pivot T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(ascend, empty[T](), optional(pivot), false, false, iterator)
+	t.Range(Unbounded[T](), Bound[T]{Item: pivot}, Ascending).Each(iterator)
 }
 
 // AscendGreaterOrEqual calls the iterator for every value in the tree within
 // the range [pivot, last], until iterator returns false.
 func (t *BTree[T]) AscendGreaterOrEqual(pivot T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(ascend, optional(pivot), empty[T](), true, false, iterator)
+	t.Range(Bound[T]{Item: pivot, Inclusive: true}, Unbounded[T](), Ascending).Each(iterator)
 }
 
 // Ascend calls the iterator for every value in the tree within the range
 // [first, last], until iterator returns false.
 func (t *BTree[T]) Ascend(iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(ascend, empty[T](), empty[T](), false, false, iterator)
+	t.Range(Unbounded[T](), Unbounded[T](), Ascending).Each(iterator)
 }
 
 // DescendRange calls the iterator for every value in the tree within the range
 // [lessOrEqual, greaterThan), until iterator returns false.
 func (t *BTree[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(descend, optional(lessOrEqual), optional(greaterThan), true, false, iterator)
+	t.Range(Bound[T]{Item: greaterThan}, Bound[T]{Item: lessOrEqual, Inclusive: true}, Descending).Each(iterator)
 }
 
 // DescendLessOrEqual calls the iterator for every value in the tree within the range
 // [pivot, first], until iterator returns false.
 func (t *BTree[T]) DescendLessOrEqual(pivot T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(descend, optional(pivot), empty[T](), true, false, iterator)
+	t.Range(Unbounded[T](), Bound[T]{Item: pivot, Inclusive: true}, Descending).Each(iterator)
 }
 
 // DescendGreaterThan calls the iterator for every value in the tree within
 // the range [last, pivot), until iterator returns false.
 func (t *BTree[T]) DescendGreaterThan(pivot T, iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
-	}
-	t.root.iterate(descend, empty[T](), optional(pivot), false, false, iterator)
+	t.Range(Bound[T]{Item: pivot}, Unbounded[T](), Descending).Each(iterator)
 }
 
 // Descend calls the iterator for every value in the tree within the range
 // [last, first], until iterator returns false.
 func (t *BTree[T]) Descend(iterator ItemIterator[T]) {
-	if t.root == nil {
-		return
+	t.Range(Unbounded[T](), Unbounded[T](), Descending).Each(iterator)
+}
+
+// PageToken marks where a page returned by Page ended so that the next page
+// can resume right after it.
+//
+// A token only remembers the last item of its page, which the next call to
+// Page seeks again, so it remains valid whatever modifications are made to
+// the tree in between, including the removal of that item.
+//
+// The zero PageToken starts from the first item of the tree in the direction
+// of the scan.
+type PageToken[T Item[T]] struct {
+	last  T
+	valid bool
+	done  bool
+}
+
+// PageAfter returns a PageToken starting right after item, which is excluded
+// from the page whether it is in the tree or not.
+func PageAfter[T Item[T]](item T) PageToken[T] {
+	return PageToken[T]{last: item, valid: true}
+}
+
+// Done returns true when the page which returned this token was the last one.
+func (p PageToken[T]) Done() bool {
+	return p.done
+}
+
+// Page returns at most limit items of the tree following start in the given
+// direction, start excluded, along with the token to pass to the next call to
+// get the following page.
+func (t *BTree[T]) Page(start PageToken[T], limit int, dir Direction) (page []T, next PageToken[T]) {
+	if start.done || limit <= 0 {
+		return nil, start
+	}
+
+	size := limit + 1
+	if size > t.length {
+		size = t.length
 	}
-	t.root.iterate(descend, empty[T](), empty[T](), false, false, iterator)
+	page = make([]T, 0, size)
+	lo, hi := Unbounded[T](), Unbounded[T]()
+	if start.valid && dir == Ascending {
+		lo = Bound[T]{Item: start.last}
+	} else if start.valid {
+		hi = Bound[T]{Item: start.last}
+	}
+	t.Range(lo, hi, dir).Limit(limit + 1).Each(func(item T) bool {
+		page = append(page, item)
+		return true
+	})
+
+	if len(page) <= limit {
+		return page, PageToken[T]{done: true}
+	}
+	page = page[:limit]
+	return page, PageAfter(page[limit-1])
 }
 
 // Get looks for the key item in the tree, returning it.  It returns
@@ -759,6 +1562,30 @@ func (t *BTree[T]) Get(key T) (_ T, _ bool) {
 	return t.root.get(key)
 }
 
+// Floor returns the greatest item of the tree lower than or equal to key, or
+// (zeroValue, false) if there is none.
+func (t *BTree[T]) Floor(key T) (_ T, _ bool) {
+	return t.root.nearest(key, Descending, true)
+}
+
+// Ceil returns the lowest item of the tree greater than or equal to key, or
+// (zeroValue, false) if there is none.
+func (t *BTree[T]) Ceil(key T) (_ T, _ bool) {
+	return t.root.nearest(key, Ascending, true)
+}
+
+// Lower returns the greatest item of the tree strictly lower than key, or
+// (zeroValue, false) if there is none.
+func (t *BTree[T]) Lower(key T) (_ T, _ bool) {
+	return t.root.nearest(key, Descending, false)
+}
+
+// Higher returns the lowest item of the tree strictly greater than key, or
+// (zeroValue, false) if there is none.
+func (t *BTree[T]) Higher(key T) (_ T, _ bool) {
+	return t.root.nearest(key, Ascending, false)
+}
+
 // Min returns the smallest item in the tree, or (zeroValue, false) if the tree is empty.
 func (t *BTree[T]) Min() (_ T, _ bool) {
 	return min(t.root)
@@ -801,6 +1628,18 @@ func (t *BTree[T]) Len() int {
 //	O(tree size):  when all nodes are owned by another tree, all nodes are
 //	    iterated over looking for nodes to add to the freelist, and due to
 //	    ownership, none are.
+//
+// Observers of the tree are notified of the deletion of every item, which
+// takes O(tree size).
 func (t *BTree[T]) Clear(addNodesToFreelist bool) {
+	var removed []T
+	if len(t.observers) > 0 && t.root != nil {
+		removed = make([]T, 0, t.length)
+		t.root.iterate(Ascending, Unbounded[T](), Unbounded[T](), func(item T) bool {
+			removed = append(removed, item)
+			return true
+		})
+	}
 	t.root, t.length = nil, 0
+	t.notifyDeleted(removed)
 }
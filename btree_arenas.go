@@ -34,6 +34,8 @@ func (t *BTree[T]) DeepCopyWithArena(a *arena.Arena) *BTree[T] {
 	t2.freelist = arena.New[FreeList[T]](a)
 	t2.freelist.freelist = arena.MakeSlice[*node[T]](a, 0, cap(t.freelist.freelist))
 	t2.degree = t.degree
+	t2.cmp = t.cmp
+	t2.strategy, t2.ordered = t.strategy, t.ordered
 	t2.length = t.length
 	t2.root = t.root.DeepCopyWithArena(a)
 
@@ -4,7 +4,9 @@ package btree
 
 import (
 	"arena"
+	"context"
 	"flag"
+	"fmt"
 	"math/rand"
 	"runtime"
 	"testing"
@@ -115,6 +117,29 @@ func BenchmarkDeepCopy(b *testing.B) {
 	runtime.GC()
 }
 
+func BenchmarkDeepCopyParallel(b *testing.B) {
+	items := rand.Perm(16392)
+
+	tr := New[*testInt](*btreeDegree)
+
+	for _, v := range items {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr2, err := tr.DeepCopyParallel(context.Background(), workers)
+				if err != nil {
+					b.Fatal(err)
+				}
+				tr2.Len()
+			}
+			runtime.GC()
+		})
+	}
+}
+
 func BenchmarkDeepCopyWithArena(b *testing.B) {
 	items := rand.Perm(16392)
 
@@ -16,6 +16,7 @@
 package btree
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"math/rand"
@@ -72,6 +73,52 @@ func testIntAllRev(t *BTree[*testInt]) (out []*testInt) {
 	return
 }
 
+// checkTree verifies the structural invariants of tr and that it holds its
+// items in strictly ascending order.
+func checkTree(t *testing.T, tr *BTree[*testInt]) {
+	t.Helper()
+	if tr.root == nil {
+		if tr.Len() != 0 {
+			t.Fatalf("nil root with len %v", tr.Len())
+		}
+		return
+	}
+	count, leafDepth := 0, -1
+	var prev *testInt
+	var walk func(n *node[*testInt], depth int)
+	walk = func(n *node[*testInt], depth int) {
+		if n != tr.root && (len(n.items) < tr.minItems() || len(n.items) > tr.maxItems()) {
+			t.Fatalf("node %v has %v items", n.items, len(n.items))
+		}
+		if len(n.children) == 0 {
+			if leafDepth < 0 {
+				leafDepth = depth
+			} else if depth != leafDepth {
+				t.Fatalf("leaf %v at depth %v, want %v", n.items, depth, leafDepth)
+			}
+		} else if len(n.children) != len(n.items)+1 {
+			t.Fatalf("node %v has %v children", n.items, len(n.children))
+		}
+		for i, item := range n.items {
+			if len(n.children) > 0 {
+				walk(n.children[i], depth+1)
+			}
+			if prev != nil && !prev.Less(item) {
+				t.Fatalf("out of order: %v then %v", *prev, *item)
+			}
+			prev = item
+			count++
+		}
+		if len(n.children) > 0 {
+			walk(n.children[len(n.children)-1], depth+1)
+		}
+	}
+	walk(tr.root, 0)
+	if count != tr.Len() {
+		t.Fatalf("len: tree has %v items, Len() returns %v", count, tr.Len())
+	}
+}
+
 func TestBTreeG(t *testing.T) {
 	tr := New[*testInt](*btreeDegree)
 	const treeSize = 100
@@ -129,7 +176,7 @@ func TestBTreeG(t *testing.T) {
 	}
 }
 
-func ExampleBTreeG() {
+func ExampleBTree() {
 	tr := New[*testInt](*btreeDegree)
 	for i := 0; i < 10; i++ {
 		ti := testInt(i)
@@ -356,6 +403,420 @@ func TestDescendGreaterThanG(t *testing.T) {
 	}
 }
 
+func TestCopyStructureG(t *testing.T) {
+	tr := New[*testInt](3)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	tr2 := tr.CopyStructure()
+	if tr2.Len() != tr.Len() {
+		t.Fatalf("len: want %v, got %v", tr.Len(), tr2.Len())
+	}
+	got, want := testIntAll(tr2), testIntAll(tr)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("item %v not shared: %p != %p", i, got[i], want[i])
+		}
+	}
+	tr2.Delete(newTestInt(50))
+	tr2.ReplaceOrInsert(newTestInt(100))
+	if want := intRange(100, false); !reflect.DeepEqual(testIntAll(tr), want) {
+		t.Fatalf("original modified:\n got: %v\nwant: %v", testIntAll(tr), want)
+	}
+}
+
+func TestCopyFuncG(t *testing.T) {
+	tr := New[*testInt](3)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	tr2 := tr.CopyFunc(func(item *testInt) *testInt {
+		return newTestInt(int(*item) * 2)
+	})
+	var want []*testInt
+	for _, v := range intRange(100, false) {
+		want = append(want, newTestInt(int(*v)*2))
+	}
+	if got := testIntAll(tr2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("copyfunc:\n got: %v\nwant: %v", got, want)
+	}
+	if want := intRange(100, false); !reflect.DeepEqual(testIntAll(tr), want) {
+		t.Fatalf("original modified:\n got: %v\nwant: %v", testIntAll(tr), want)
+	}
+}
+
+func TestDeepCopyParallelG(t *testing.T) {
+	tr := New[*testInt](3)
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	tr2, err := tr.DeepCopyParallel(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr2.Len() != tr.Len() {
+		t.Fatalf("len: want %v, got %v", tr.Len(), tr2.Len())
+	}
+	got, want := testIntAll(tr2), testIntAll(tr)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mismatch:\n got: %v\nwant: %v", got, want)
+	}
+	for i := range want {
+		if got[i] == want[i] {
+			t.Fatalf("item %v shared between copies", i)
+		}
+	}
+	var check func(n *node[*testInt])
+	check = func(n *node[*testInt]) {
+		if n.t != tr2 {
+			t.Fatalf("node %v does not point to its tree", n.items)
+		}
+		for _, c := range n.children {
+			check(c)
+		}
+	}
+	check(tr2.root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if tr2, err := tr.DeepCopyParallel(ctx, 4); err != context.Canceled || tr2 != nil {
+		t.Fatalf("canceled copy: got %v, %v", tr2, err)
+	}
+	if tr2, err := New[*testInt](*btreeDegree).DeepCopyParallel(ctx, 4); err != context.Canceled || tr2 != nil {
+		t.Fatalf("canceled copy of an empty tree: got %v, %v", tr2, err)
+	}
+}
+
+func TestReplaceOrInsertManyG(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		// runs is the number of sorted runs in the batch, 0 for a random batch.
+		for _, runs := range []int{0, 1, 10, 1000} {
+			tr, want := New[*testInt](degree), New[*testInt](degree)
+			for _, v := range rand.Perm(500) {
+				if v%3 == 0 {
+					tr.ReplaceOrInsert(newTestInt(v))
+					want.ReplaceOrInsert(newTestInt(v))
+				}
+			}
+			var batch []*testInt
+			for _, v := range rand.Perm(1000) {
+				batch = append(batch, newTestInt(v%800))
+			}
+			for i := 0; runs > 0 && i < len(batch); i += len(batch) / runs {
+				run := batch[i : i+len(batch)/runs]
+				sort.SliceStable(run, func(i, j int) bool { return *run[i] < *run[j] })
+			}
+			input := append([]*testInt(nil), batch...)
+
+			var wantReplaced []*testInt
+			for _, item := range batch {
+				if old, ok := want.ReplaceOrInsert(item); ok {
+					wantReplaced = append(wantReplaced, old)
+				}
+			}
+			sort.SliceStable(wantReplaced, func(i, j int) bool { return *wantReplaced[i] < *wantReplaced[j] })
+
+			replaced := tr.ReplaceOrInsertMany(batch)
+			checkTree(t, tr)
+			if !reflect.DeepEqual(batch, input) {
+				t.Fatalf("degree %v runs %v: batch modified", degree, runs)
+			}
+			if got, want := testIntAll(tr), testIntAll(want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v runs %v: mismatch:\n got: %v\nwant: %v", degree, runs, got, want)
+			}
+			sort.SliceStable(replaced, func(i, j int) bool { return *replaced[i] < *replaced[j] })
+			if !reflect.DeepEqual(replaced, wantReplaced) {
+				t.Fatalf("degree %v runs %v: replaced:\n got: %v\nwant: %v", degree, runs, replaced, wantReplaced)
+			}
+		}
+	}
+}
+
+func TestDeleteManyG(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		for _, n := range []int{1, 10, 100, 500, 1000, 1200} {
+			fl := NewFreeList[*testInt](1000)
+			tr := NewWithFreeList[*testInt](degree, fl)
+			for _, v := range rand.Perm(1000) {
+				tr.ReplaceOrInsert(newTestInt(v))
+			}
+			var keys []*testInt
+			for _, v := range rand.Perm(1200)[:n] {
+				keys = append(keys, newTestInt(v))
+			}
+			input := append([]*testInt(nil), keys...)
+
+			var want []*testInt
+			for _, key := range keys {
+				if *key < 1000 {
+					want = append(want, key)
+				}
+			}
+			sort.Slice(want, func(i, j int) bool { return *want[i] < *want[j] })
+
+			removed := tr.DeleteMany(keys)
+			checkTree(t, tr)
+			if !reflect.DeepEqual(keys, input) {
+				t.Fatalf("degree %v n %v: keys modified", degree, n)
+			}
+			if !reflect.DeepEqual(removed, want) {
+				t.Fatalf("degree %v n %v: removed:\n got: %v\nwant: %v", degree, n, removed, want)
+			}
+			for _, key := range keys {
+				if tr.Has(key) {
+					t.Fatalf("degree %v n %v: %v still in tree", degree, n, *key)
+				}
+			}
+			if tr.Len() != 1000-len(want) {
+				t.Fatalf("degree %v n %v: len: want %v, got %v", degree, n, 1000-len(want), tr.Len())
+			}
+			if n >= 500 && len(fl.freelist) == 0 {
+				t.Fatalf("degree %v n %v: no node returned to the freelist", degree, n)
+			}
+		}
+	}
+}
+
+func TestUpsertG(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		tr := New[*testInt](degree)
+		want := map[int]*testInt{}
+		for i := 0; i < 5000; i++ {
+			key := newTestInt(rand.Intn(300))
+			action := UpsertAction(rand.Intn(4))
+			item := newTestInt(int(*key))
+			got := tr.Upsert(key, func(old *testInt, exists bool) (*testInt, UpsertAction) {
+				if w, ok := want[int(*key)]; ok != exists || w != old {
+					t.Fatalf("degree %v: upsert %v: got (%v, %v), want (%v, %v)", degree, *key, old, exists, w, ok)
+				}
+				return item, action
+			})
+			_, exists := want[int(*key)]
+			switch {
+			case action == UpsertReplace && exists, action == UpsertInsert && !exists:
+				want[int(*key)] = item
+			case action == UpsertDelete && exists:
+				delete(want, int(*key))
+			default:
+				action = UpsertKeep
+			}
+			if got != action {
+				t.Fatalf("degree %v: upsert %v: got action %v, want %v", degree, *key, got, action)
+			}
+		}
+		checkTree(t, tr)
+		if tr.Len() != len(want) {
+			t.Fatalf("degree %v: len: want %v, got %v", degree, len(want), tr.Len())
+		}
+		for k, w := range want {
+			if item, ok := tr.Get(newTestInt(k)); !ok || item != w {
+				t.Fatalf("degree %v: get %v: got %v, want %v", degree, k, item, w)
+			}
+		}
+	}
+}
+
+func TestUpsertActionsG(t *testing.T) {
+	tr := New[*testInt](2)
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(newTestInt(i * 2))
+	}
+	for _, test := range []struct {
+		key    int
+		action UpsertAction
+		want   UpsertAction
+		exists bool
+	}{
+		{4, UpsertReplace, UpsertReplace, true},
+		{5, UpsertReplace, UpsertKeep, false},
+		{6, UpsertInsert, UpsertKeep, true},
+		{7, UpsertInsert, UpsertInsert, true},
+		{8, UpsertDelete, UpsertDelete, false},
+		{9, UpsertDelete, UpsertKeep, false},
+	} {
+		item := newTestInt(test.key)
+		before, _ := tr.Get(item)
+		if got := tr.Upsert(item, func(*testInt, bool) (*testInt, UpsertAction) {
+			return item, test.action
+		}); got != test.want {
+			t.Fatalf("upsert %v with %v: got action %v, want %v", test.key, test.action, got, test.want)
+		}
+		after, exists := tr.Get(item)
+		if exists != test.exists {
+			t.Fatalf("upsert %v with %v: exists %v, want %v", test.key, test.action, exists, test.exists)
+		}
+		if test.want == UpsertKeep && after != before {
+			t.Fatalf("upsert %v with %v: item changed from %v to %v", test.key, test.action, before, after)
+		}
+		if test.want != UpsertKeep && exists && after != item {
+			t.Fatalf("upsert %v with %v: item wasn't stored", test.key, test.action)
+		}
+	}
+	checkTree(t, tr)
+}
+
+func TestUpsertOrderG(t *testing.T) {
+	tr := New[*testInt](2)
+	for _, v := range rand.Perm(10) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic when changing the item position")
+		}
+	}()
+	tr.Upsert(newTestInt(5), func(old *testInt, exists bool) (*testInt, UpsertAction) {
+		return newTestInt(int(*old) + 10), UpsertReplace
+	})
+}
+
+func TestNearestG(t *testing.T) {
+	tr := New[*testInt](2)
+	for _, test := range []struct {
+		name string
+		fn   func(*testInt) (*testInt, bool)
+	}{{"floor", tr.Floor}, {"ceil", tr.Ceil}, {"lower", tr.Lower}, {"higher", tr.Higher}} {
+		if item, ok := test.fn(newTestInt(0)); ok || item != nil {
+			t.Fatalf("empty %v, got %+v", test.name, item)
+		}
+	}
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(newTestInt(v * 2))
+	}
+	// nearest returns the first item in the given direction among the
+	// ones matching ok, or -1.
+	nearest := func(dir Direction, ok func(int) bool) int {
+		want := -1
+		for v := 0; v < 200; v += 2 {
+			if ok(v) && (want < 0 || dir == Ascending && v < want || dir == Descending && v > want) {
+				want = v
+			}
+		}
+		return want
+	}
+	for k := -3; k <= 201; k++ {
+		key := newTestInt(k)
+		for _, test := range []struct {
+			name string
+			fn   func(*testInt) (*testInt, bool)
+			want int
+		}{
+			{"floor", tr.Floor, nearest(Descending, func(v int) bool { return v <= k })},
+			{"ceil", tr.Ceil, nearest(Ascending, func(v int) bool { return v >= k })},
+			{"lower", tr.Lower, nearest(Descending, func(v int) bool { return v < k })},
+			{"higher", tr.Higher, nearest(Ascending, func(v int) bool { return v > k })},
+		} {
+			item, ok := test.fn(key)
+			if test.want < 0 {
+				if ok || item != nil {
+					t.Fatalf("%v(%v): want none, got %v", test.name, k, *item)
+				}
+				continue
+			}
+			if !ok || int(*item) != test.want {
+				t.Fatalf("%v(%v): want %v, got %v %v", test.name, k, test.want, item, ok)
+			}
+		}
+	}
+}
+
+func TestPageG(t *testing.T) {
+	tr := New[*testInt](3)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	for _, dir := range []Direction{Ascending, Descending} {
+		for _, limit := range []int{1, 7, 10, 100, 1000} {
+			var got []*testInt
+			var page []*testInt
+			pages := 0
+			for next := (PageToken[*testInt]{}); !next.Done(); pages++ {
+				page, next = tr.Page(next, limit, dir)
+				if len(page) > limit {
+					t.Fatalf("dir %v limit %v: page of %v items", dir, limit, len(page))
+				}
+				got = append(got, page...)
+			}
+			if want := intRange(100, dir == Descending); !reflect.DeepEqual(got, want) {
+				t.Fatalf("dir %v limit %v: mismatch:\n got: %v\nwant: %v", dir, limit, got, want)
+			}
+			if want := (100 + limit - 1) / limit; pages != want {
+				t.Fatalf("dir %v limit %v: want %v pages, got %v", dir, limit, want, pages)
+			}
+		}
+	}
+
+	page, next := tr.Page(PageAfter(newTestInt(49)), 10, Ascending)
+	if want := intRange(100, false)[50:60]; !reflect.DeepEqual(page, want) {
+		t.Fatalf("page after 49:\n got: %v\nwant: %v", page, want)
+	}
+	// The token must survive the removal of the item it stopped at, as well as
+	// insertions on both sides of it.
+	tr.Delete(newTestInt(59))
+	tr.ReplaceOrInsert(newTestInt(-1))
+	tr.ReplaceOrInsert(newTestInt(100))
+	page, _ = tr.Page(next, 5, Ascending)
+	if want := intRange(100, false)[60:65]; !reflect.DeepEqual(page, want) {
+		t.Fatalf("page after modification:\n got: %v\nwant: %v", page, want)
+	}
+	page, _ = tr.Page(next, 5, Descending)
+	if want := intRange(100, true)[41:46]; !reflect.DeepEqual(page, want) {
+		t.Fatalf("backward page after modification:\n got: %v\nwant: %v", page, want)
+	}
+}
+
+func TestRangeG(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		tr := New[*testInt](degree)
+		for _, v := range rand.Perm(50) {
+			tr.ReplaceOrInsert(newTestInt(v * 2))
+		}
+		bounds := []Bound[*testInt]{Unbounded[*testInt]()}
+		for _, v := range []int{-1, 0, 1, 10, 11, 50, 98, 99, 100} {
+			bounds = append(bounds, Bound[*testInt]{Item: newTestInt(v)}, Bound[*testInt]{Item: newTestInt(v), Inclusive: true})
+		}
+		// within reports whether v is above lo, or below hi if upper is true.
+		within := func(v int, b Bound[*testInt], upper bool) bool {
+			switch {
+			case b.Unbounded:
+				return true
+			case upper:
+				return v < int(*b.Item) || b.Inclusive && v == int(*b.Item)
+			default:
+				return v > int(*b.Item) || b.Inclusive && v == int(*b.Item)
+			}
+		}
+		for _, lo := range bounds {
+			for _, hi := range bounds {
+				for _, dir := range []Direction{Ascending, Descending} {
+					var all []*testInt
+					for _, v := range intRange(100, dir == Descending) {
+						if int(*v)%2 == 0 && within(int(*v), lo, false) && within(int(*v), hi, true) {
+							all = append(all, v)
+						}
+					}
+					for _, offset := range []int{0, 3} {
+						for _, limit := range []int{-1, 0, 1, 5} {
+							want := all[:0:0]
+							if offset < len(all) {
+								want = all[offset:]
+							}
+							if limit >= 0 && limit < len(want) {
+								want = want[:limit]
+							}
+							got := tr.Range(lo, hi, dir).Offset(offset).Limit(limit).Items()
+							if len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+								t.Fatalf("degree %v range(%+v, %+v, %v) offset %v limit %v:\n got: %v\nwant: %v",
+									degree, lo, hi, dir, offset, limit, got, want)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
 const benchmarkTreeSize = 10000
 
 func BenchmarkInsertG(b *testing.B) {
@@ -375,6 +836,37 @@ func BenchmarkInsertG(b *testing.B) {
 	}
 }
 
+func BenchmarkReplaceOrInsertManyG(b *testing.B) {
+	sorted := make([]*testInt, benchmarkTreeSize)
+	for i := range sorted {
+		sorted[i] = newTestInt(i)
+	}
+	unsorted := make([]*testInt, benchmarkTreeSize)
+	for i, v := range rand.Perm(benchmarkTreeSize) {
+		unsorted[i] = newTestInt(v)
+	}
+	for _, bench := range []struct {
+		name  string
+		batch []*testInt
+	}{{"sorted", sorted}, {"unsorted", unsorted}} {
+		name, batch := bench.name, bench.batch
+		b.Run(name+"/ReplaceOrInsert", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr := New[*testInt](*btreeDegree)
+				for _, item := range batch {
+					tr.ReplaceOrInsert(item)
+				}
+			}
+		})
+		b.Run(name+"/ReplaceOrInsertMany", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr := New[*testInt](*btreeDegree)
+				tr.ReplaceOrInsertMany(batch)
+			}
+		})
+	}
+}
+
 func BenchmarkSeekG(b *testing.B) {
 	b.StopTimer()
 	size := 100000
@@ -390,6 +882,21 @@ func BenchmarkSeekG(b *testing.B) {
 	}
 }
 
+func BenchmarkFloorG(b *testing.B) {
+	b.StopTimer()
+	size := 100000
+	insertP := rand.Perm(size)
+	tr := New[*testInt](*btreeDegree)
+	for _, item := range insertP {
+		tr.ReplaceOrInsert(newTestInt(item * 2))
+	}
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		tr.Floor(newTestInt(i % (2 * size)))
+	}
+}
+
 func BenchmarkDeleteInsertG(b *testing.B) {
 	b.StopTimer()
 	insertP := rand.Perm(benchmarkTreeSize)
@@ -430,6 +937,51 @@ func BenchmarkDeleteG(b *testing.B) {
 	}
 }
 
+func BenchmarkDeleteManyG(b *testing.B) {
+	insertP := rand.Perm(benchmarkTreeSize)
+	var keys []*testInt
+	for _, v := range rand.Perm(benchmarkTreeSize)[:benchmarkTreeSize/4] {
+		keys = append(keys, newTestInt(v))
+	}
+	b.Run("Delete", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tr := New[*testInt](*btreeDegree)
+			for _, v := range insertP {
+				tr.ReplaceOrInsert(newTestInt(v))
+			}
+			b.StartTimer()
+			for _, key := range keys {
+				tr.Delete(key)
+			}
+		}
+	})
+	b.Run("DeleteMany", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tr := New[*testInt](*btreeDegree)
+			for _, v := range insertP {
+				tr.ReplaceOrInsert(newTestInt(v))
+			}
+			b.StartTimer()
+			tr.DeleteMany(keys)
+		}
+	})
+	sorted := append([]*testInt(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return *sorted[i] < *sorted[j] })
+	b.Run("DeleteManySorted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tr := New[*testInt](*btreeDegree)
+			for _, v := range insertP {
+				tr.ReplaceOrInsert(newTestInt(v))
+			}
+			b.StartTimer()
+			tr.DeleteMany(sorted)
+		}
+	})
+}
+
 func BenchmarkGetG(b *testing.B) {
 	b.StopTimer()
 	insertP := rand.Perm(benchmarkTreeSize)
@@ -675,3 +1227,87 @@ func BenchmarkDeleteAndRestoreG(b *testing.B) {
 		}
 	})
 }
+
+// testOrderedInt is an Ordered value item.
+type testOrderedInt int
+
+func (a testOrderedInt) Less(b testOrderedInt) bool { return a < b }
+func (a testOrderedInt) DeepCopy() testOrderedInt   { return a }
+
+func TestSearchStrategy(t *testing.T) {
+	for _, strategy := range []SearchStrategy{SearchBinary, SearchLinear, SearchAdaptive} {
+		for _, degree := range []int{2, 3, 16} {
+			tl := New[*testInt](degree)
+			tc := New[testCmpKey](degree)
+			to := NewOrdered[testOrderedInt](degree)
+			tl.SetSearchStrategy(strategy)
+			tc.SetSearchStrategy(strategy)
+			to.SetSearchStrategy(strategy)
+			model := map[int]bool{}
+			for i := 0; i < 3000; i++ {
+				v := rand.Intn(500)
+				var ok []bool
+				switch rand.Intn(3) {
+				case 0, 1:
+					_, ok1 := tl.ReplaceOrInsert(newTestInt(v))
+					_, ok2 := tc.ReplaceOrInsert(testCmpKey(fmt.Sprintf("%03d", v)))
+					_, ok3 := to.ReplaceOrInsert(testOrderedInt(v))
+					ok = []bool{ok1, ok2, ok3, model[v]}
+					model[v] = true
+				case 2:
+					_, ok1 := tl.Delete(newTestInt(v))
+					_, ok2 := tc.Delete(testCmpKey(fmt.Sprintf("%03d", v)))
+					_, ok3 := to.Delete(testOrderedInt(v))
+					ok = []bool{ok1, ok2, ok3, model[v]}
+					delete(model, v)
+				}
+				if ok[0] != ok[3] || ok[1] != ok[3] || ok[2] != ok[3] {
+					t.Fatalf("strategy %v, degree %v: %v: got %v, want %v", strategy, degree, v, ok[:3], ok[3])
+				}
+			}
+			for v := 0; v < 500; v++ {
+				if tl.Has(newTestInt(v)) != model[v] || tc.Has(testCmpKey(fmt.Sprintf("%03d", v))) != model[v] || to.Has(testOrderedInt(v)) != model[v] {
+					t.Fatalf("strategy %v, degree %v: has %v isn't %v", strategy, degree, v, model[v])
+				}
+			}
+			if c := to.DeepCopy(); c.ordered == nil || c.strategy != strategy {
+				t.Fatalf("strategy %v, degree %v: copy lost the search settings", strategy, degree)
+			}
+		}
+	}
+}
+
+func BenchmarkSearchStrategy(b *testing.B) {
+	insertP := rand.Perm(benchmarkTreeSize)
+	for _, degree := range []int{2, 4, 8, 16, 32, 64} {
+		for _, strategy := range []struct {
+			name     string
+			strategy SearchStrategy
+		}{{"Binary", SearchBinary}, {"Linear", SearchLinear}, {"Adaptive", SearchAdaptive}} {
+			b.Run(fmt.Sprintf("Degree%d/Less/%s", degree, strategy.name), func(b *testing.B) {
+				tr := New[*testInt](degree)
+				tr.SetSearchStrategy(strategy.strategy)
+				keys := make([]*testInt, len(insertP))
+				for i, v := range insertP {
+					keys[i] = newTestInt(v)
+					tr.ReplaceOrInsert(keys[i])
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					tr.Get(keys[i%len(keys)])
+				}
+			})
+			b.Run(fmt.Sprintf("Degree%d/Ordered/%s", degree, strategy.name), func(b *testing.B) {
+				tr := NewOrdered[testOrderedInt](degree)
+				tr.SetSearchStrategy(strategy.strategy)
+				for _, v := range insertP {
+					tr.ReplaceOrInsert(testOrderedInt(v))
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					tr.Get(testOrderedInt(insertP[i%len(insertP)]))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package btree
+
+// Comparable can be implemented by items in addition to Item, in which case
+// the tree orders them with a single call to Compare where Less would have to
+// be called twice to tell whether two items are equal.  This pays off for
+// items whose comparison is expensive, such as long strings or composite
+// keys.  Items which aren't pointers are converted to an interface, which may
+// allocate, once per visited node: Comparable is best implemented by pointer
+// items.  Less is still used where a single call is enough, such as to sort
+// the batches given to ReplaceOrInsertMany and DeleteMany.
+//
+// Compare must return a negative number, zero or a positive number when the
+// item is respectively lower than, equal to or greater than the given one,
+// consistently with Less.
+type Comparable[T any] interface {
+	Compare(T) int
+}
+
+// isComparable returns whether T implements Comparable[T].
+func isComparable[T Item[T]]() bool {
+	var zero T
+	_, ok := any(zero).(Comparable[T])
+	return ok
+}
+
+// compare returns -1, 0 or 1 when a is respectively lower than, equal to or
+// greater than b.
+func (t *BTree[T]) compare(a, b T) int {
+	if t.cmp {
+		switch c := any(a).(Comparable[T]).Compare(b); {
+		case c < 0:
+			return -1
+		case c > 0:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case a.Less(b):
+		return -1
+	case b.Less(a):
+		return 1
+	}
+	return 0
+}
+
+// find returns the index where the given item should be inserted into n, and
+// whether an equal item is already there.
+func (n *node[T]) find(item T) (index int, found bool) {
+	return n.t.search(n.items, item)
+}
+
+// findCompare is find for Comparable items.
+func (s items[T]) findCompare(item Comparable[T]) (index int, found bool) {
+	lo, hi := 0, len(s)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		switch c := item.Compare(s[m]); {
+		case c == 0:
+			return m, true
+		case c < 0:
+			hi = m
+		default:
+			lo = m + 1
+		}
+	}
+	return lo, false
+}
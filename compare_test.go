@@ -0,0 +1,205 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testKey is a string only ordered by Less.
+type testKey string
+
+func (k testKey) Less(k2 testKey) bool { return k < k2 }
+func (k testKey) DeepCopy() testKey    { return k }
+
+// testCmpKey is a string which is also Comparable.
+type testCmpKey string
+
+func (k testCmpKey) Less(k2 testCmpKey) bool   { return k < k2 }
+func (k testCmpKey) DeepCopy() testCmpKey      { return k }
+func (k testCmpKey) Compare(k2 testCmpKey) int { return strings.Compare(string(k), string(k2)) }
+
+// testRecordKey is a composite key only ordered by Less, which has to compare
+// each field twice to find out whether it is equal.
+type testRecordKey struct {
+	tenant, bucket, name string
+}
+
+func (k *testRecordKey) Less(k2 *testRecordKey) bool {
+	if k.tenant != k2.tenant {
+		return k.tenant < k2.tenant
+	}
+	if k.bucket != k2.bucket {
+		return k.bucket < k2.bucket
+	}
+	return k.name < k2.name
+}
+
+func (k *testRecordKey) DeepCopy() *testRecordKey {
+	k2 := *k
+	return &k2
+}
+
+// testCmpRecordKey is a testRecordKey which is also Comparable.
+type testCmpRecordKey struct {
+	testRecordKey
+}
+
+func (k *testCmpRecordKey) Less(k2 *testCmpRecordKey) bool {
+	return k.testRecordKey.Less(&k2.testRecordKey)
+}
+
+func (k *testCmpRecordKey) DeepCopy() *testCmpRecordKey {
+	k2 := *k
+	return &k2
+}
+
+func (k *testCmpRecordKey) Compare(k2 *testCmpRecordKey) int {
+	if c := strings.Compare(k.tenant, k2.tenant); c != 0 {
+		return c
+	}
+	if c := strings.Compare(k.bucket, k2.bucket); c != 0 {
+		return c
+	}
+	return strings.Compare(k.name, k2.name)
+}
+
+// testRecordKeys returns n composite keys sharing long prefixes, in random
+// order.
+func testRecordKeys(n int) ([]*testRecordKey, []*testCmpRecordKey) {
+	prefix := strings.Repeat("x", 32)
+	keys := make([]*testRecordKey, n)
+	cmpKeys := make([]*testCmpRecordKey, n)
+	for i, v := range rand.Perm(n) {
+		keys[i] = &testRecordKey{tenant: prefix, bucket: fmt.Sprint(prefix, v%10), name: fmt.Sprintf("%s%08d", prefix, v)}
+		cmpKeys[i] = &testCmpRecordKey{*keys[i]}
+	}
+	return keys, cmpKeys
+}
+
+func TestComparable(t *testing.T) {
+	if New[testKey](2).cmp || !New[testCmpKey](2).cmp {
+		t.Fatal("Comparable wasn't detected")
+	}
+
+	for _, degree := range []int{2, 3, 32} {
+		tl, tc := New[testKey](degree), New[testCmpKey](degree)
+		for i := 0; i < 5000; i++ {
+			k := fmt.Sprint(rand.Intn(1000))
+			var ok1, ok2 bool
+			switch rand.Intn(4) {
+			case 0, 1:
+				_, ok1 = tl.ReplaceOrInsert(testKey(k))
+				_, ok2 = tc.ReplaceOrInsert(testCmpKey(k))
+			case 2:
+				_, ok1 = tl.Delete(testKey(k))
+				_, ok2 = tc.Delete(testCmpKey(k))
+			case 3:
+				_, ok1 = tl.Get(testKey(k))
+				_, ok2 = tc.Get(testCmpKey(k))
+			}
+			if ok1 != ok2 {
+				t.Fatalf("degree %v: %v: %v with Less, %v with Compare", degree, k, ok1, ok2)
+			}
+		}
+		var want, got []string
+		tl.Ascend(func(k testKey) bool {
+			want = append(want, string(k))
+			return true
+		})
+		tc.Ascend(func(k testCmpKey) bool {
+			got = append(got, string(k))
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %v: got %v, want %v", degree, got, want)
+		}
+	}
+}
+
+// testLessCount counts the calls to the Less method of testCountKey.
+var testLessCount int
+
+// testCountKey is a Comparable int counting the calls to its Less method.
+type testCountKey int
+
+func (k testCountKey) Less(k2 testCountKey) bool {
+	testLessCount++
+	return k < k2
+}
+
+func (k testCountKey) DeepCopy() testCountKey { return k }
+
+func (k testCountKey) Compare(k2 testCountKey) int { return int(k) - int(k2) }
+
+func TestComparableAvoidsLess(t *testing.T) {
+	tr := New[testCountKey](2)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(testCountKey(v))
+	}
+	testLessCount = 0
+
+	tr.Range(Bound[testCountKey]{Item: 10, Inclusive: true}, Bound[testCountKey]{Item: 20, Inclusive: true}, Ascending).Items()
+	tr.Range(Bound[testCountKey]{Item: 10}, Bound[testCountKey]{Item: 20}, Descending).Items()
+	tr.Upsert(30, func(old testCountKey, exists bool) (testCountKey, UpsertAction) {
+		return old, UpsertReplace
+	})
+	tr.Get(50)
+	if testLessCount != 0 {
+		t.Fatalf("Less was called %v times", testLessCount)
+	}
+}
+
+func BenchmarkInsertCompare(b *testing.B) {
+	keys, cmpKeys := testRecordKeys(benchmarkTreeSize)
+	b.Run("Less", func(b *testing.B) {
+		for i := 0; i < b.N; {
+			tr := New[*testRecordKey](*btreeDegree)
+			for _, k := range keys {
+				tr.ReplaceOrInsert(k)
+				if i++; i >= b.N {
+					return
+				}
+			}
+		}
+	})
+	b.Run("Compare", func(b *testing.B) {
+		for i := 0; i < b.N; {
+			tr := New[*testCmpRecordKey](*btreeDegree)
+			for _, k := range cmpKeys {
+				tr.ReplaceOrInsert(k)
+				if i++; i >= b.N {
+					return
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGetCompare(b *testing.B) {
+	keys, cmpKeys := testRecordKeys(benchmarkTreeSize)
+	b.Run("Less", func(b *testing.B) {
+		tr := New[*testRecordKey](*btreeDegree)
+		for _, k := range keys {
+			tr.ReplaceOrInsert(k)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Get(keys[i%len(keys)])
+		}
+	})
+	b.Run("Compare", func(b *testing.B) {
+		tr := New[*testCmpRecordKey](*btreeDegree)
+		for _, k := range cmpKeys {
+			tr.ReplaceOrInsert(k)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Get(cmpKeys[i%len(cmpKeys)])
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package btree
+
+// DiffEntry is a difference between two trees reported by Diff.  Old is the
+// zero value for insertions, and New the zero value for deletions.
+type DiffEntry[T Item[T]] struct {
+	Op       Op
+	Old, New T
+}
+
+// Diff walks a and b in ascending order and calls fn, until it returns false,
+// with the changes turning a into b:
+//
+//   - OpInsert for the items of b which aren't in a,
+//   - OpDelete for the items of a which aren't in b,
+//   - OpReplace for the items of a whose equal item in b isn't the same
+//     according to eq.
+//
+// A nil eq considers equal items to be the same, so that only insertions and
+// deletions are reported.
+//
+// Subtrees shared by both trees are skipped without being walked.  Only a tree
+// diffed with itself shares nodes for now, since copies don't share theirs,
+// but this is what a copy-on-write clone would make diffing proportional to
+// the size of the differences rather than to the size of the trees.
+func Diff[T Item[T]](a, b *BTree[T], eq func(x, y T) bool, fn func(DiffEntry[T]) bool) {
+	ca, cb := newDiffCursor(a), newDiffCursor(b)
+	for {
+		ea, oka := ca.peek()
+		eb, okb := cb.peek()
+		switch {
+		case !oka && !okb:
+			return
+		case !oka:
+			cb.pop()
+			if eb.n != nil {
+				cb.expand(eb)
+			} else if !fn(DiffEntry[T]{Op: OpInsert, New: eb.item}) {
+				return
+			}
+			continue
+		case !okb:
+			ca.pop()
+			if ea.n != nil {
+				ca.expand(ea)
+			} else if !fn(DiffEntry[T]{Op: OpDelete, Old: ea.item}) {
+				return
+			}
+			continue
+		}
+
+		if ea.n != nil || eb.n != nil {
+			switch {
+			case ea.n == eb.n:
+				ca.pop()
+				cb.pop()
+			case eb.n == nil || ea.n != nil && ea.height > eb.height:
+				ca.pop()
+				ca.expand(ea)
+			case ea.n == nil || eb.height > ea.height:
+				cb.pop()
+				cb.expand(eb)
+			default:
+				ca.pop()
+				ca.expand(ea)
+				cb.pop()
+				cb.expand(eb)
+			}
+			continue
+		}
+
+		var e DiffEntry[T]
+		switch a.compare(ea.item, eb.item) {
+		case -1:
+			ca.pop()
+			e = DiffEntry[T]{Op: OpDelete, Old: ea.item}
+		case 1:
+			cb.pop()
+			e = DiffEntry[T]{Op: OpInsert, New: eb.item}
+		default:
+			ca.pop()
+			cb.pop()
+			if eq == nil || eq(ea.item, eb.item) {
+				continue
+			}
+			e = DiffEntry[T]{Op: OpReplace, Old: ea.item, New: eb.item}
+		}
+		if !fn(e) {
+			return
+		}
+	}
+}
+
+// diffElement is either a whole subtree of a given height, when n isn't nil,
+// or a single item.
+type diffElement[T Item[T]] struct {
+	n      *node[T]
+	height int
+	item   T
+}
+
+// diffCursor walks a tree in order, expanding subtrees only when asked to.
+// The next element is at the top of the stack.
+type diffCursor[T Item[T]] struct {
+	stack []diffElement[T]
+}
+
+func newDiffCursor[T Item[T]](t *BTree[T]) *diffCursor[T] {
+	c := &diffCursor[T]{}
+	if t.root != nil && len(t.root.items) > 0 {
+		height := 1
+		for n := t.root; len(n.children) > 0; n = n.children[0] {
+			height++
+		}
+		c.stack = append(c.stack, diffElement[T]{n: t.root, height: height})
+	}
+	return c
+}
+
+func (c *diffCursor[T]) peek() (diffElement[T], bool) {
+	if len(c.stack) == 0 {
+		return diffElement[T]{}, false
+	}
+	return c.stack[len(c.stack)-1], true
+}
+
+func (c *diffCursor[T]) pop() {
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// expand pushes the children and items of the subtree e, which must have been
+// popped, so that its first child or item comes next.
+func (c *diffCursor[T]) expand(e diffElement[T]) {
+	n := e.n
+	if len(n.children) > 0 {
+		c.stack = append(c.stack, diffElement[T]{n: n.children[len(n.items)], height: e.height - 1})
+	}
+	for i := len(n.items) - 1; i >= 0; i-- {
+		c.stack = append(c.stack, diffElement[T]{item: n.items[i]})
+		if len(n.children) > 0 {
+			c.stack = append(c.stack, diffElement[T]{n: n.children[i], height: e.height - 1})
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		for i := 0; i < 50; i++ {
+			a, b := New[*testInt](degree), New[*testInt](degree)
+			for _, v := range rand.Perm(300)[:rand.Intn(300)] {
+				a.ReplaceOrInsert(newTestInt(v))
+			}
+			for _, v := range rand.Perm(300)[:rand.Intn(300)] {
+				b.ReplaceOrInsert(newTestInt(v))
+			}
+			// Items whose value is a multiple of 3 changed.
+			eq := func(x, y *testInt) bool { return *x%3 != 0 }
+
+			var want []DiffEntry[*testInt]
+			for v := 0; v < 300; v++ {
+				x, inA := a.Get(newTestInt(v))
+				y, inB := b.Get(newTestInt(v))
+				switch {
+				case inA && !inB:
+					want = append(want, DiffEntry[*testInt]{Op: OpDelete, Old: x})
+				case !inA && inB:
+					want = append(want, DiffEntry[*testInt]{Op: OpInsert, New: y})
+				case inA && inB && !eq(x, y):
+					want = append(want, DiffEntry[*testInt]{Op: OpReplace, Old: x, New: y})
+				}
+			}
+
+			var got []DiffEntry[*testInt]
+			Diff(a, b, eq, func(e DiffEntry[*testInt]) bool {
+				got = append(got, e)
+				return true
+			})
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v: diff\n got: %v\nwant: %v", degree, got, want)
+			}
+
+			if len(want) > 1 {
+				got = got[:0]
+				Diff(a, b, eq, func(e DiffEntry[*testInt]) bool {
+					got = append(got, e)
+					return len(got) < 2
+				})
+				if !reflect.DeepEqual(got, want[:2]) {
+					t.Fatalf("degree %v: stopped diff\n got: %v\nwant: %v", degree, got, want[:2])
+				}
+			}
+		}
+	}
+}
+
+func TestDiffSharedNodes(t *testing.T) {
+	a := New[*testInt](3)
+	for _, v := range rand.Perm(1000) {
+		a.ReplaceOrInsert(newTestInt(v))
+	}
+	calls := 0
+	eq := func(x, y *testInt) bool {
+		calls++
+		return x == y
+	}
+
+	Diff(a, a, eq, func(e DiffEntry[*testInt]) bool {
+		t.Fatalf("unexpected %+v", e)
+		return false
+	})
+	if calls != 0 {
+		t.Fatalf("eq called %v times diffing a tree with itself", calls)
+	}
+
+	// b shares every node with a but the root, whose first item changed.
+	b := New[*testInt](3)
+	b.root = &node[*testInt]{
+		t:        b,
+		items:    append(items[*testInt](nil), a.root.items...),
+		children: a.root.children,
+	}
+	b.length = a.length
+	b.root.items[0] = newTestInt(int(*a.root.items[0]))
+
+	var got []DiffEntry[*testInt]
+	Diff(a, b, eq, func(e DiffEntry[*testInt]) bool {
+		got = append(got, e)
+		return true
+	})
+	want := []DiffEntry[*testInt]{{Op: OpReplace, Old: a.root.items[0], New: b.root.items[0]}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff\n got: %v\nwant: %v", got, want)
+	}
+	if calls != len(a.root.items) {
+		t.Fatalf("eq called %v times, want %v", calls, len(a.root.items))
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	t1, t2 := New[*testInt](*btreeDegree), New[*testInt](*btreeDegree)
+	for _, v := range rand.Perm(10000) {
+		t1.ReplaceOrInsert(newTestInt(v))
+		if v%100 != 0 {
+			t2.ReplaceOrInsert(newTestInt(v))
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Diff(t1, t2, nil, func(DiffEntry[*testInt]) bool { return true })
+	}
+}
@@ -0,0 +1,501 @@
+// Package durable makes a btree.BTree survive restarts.
+//
+// A durable Tree appends every modification made to its in-memory B-Tree to
+// a write-ahead log before applying it.  The log is periodically compacted
+// into a snapshot holding the sorted items of the tree, and opening a Tree
+// loads the snapshot then replays the log on top of it.
+//
+// Both files are made of records framed as:
+//
+//	length  uint32, little endian, length of the payload
+//	crc     uint32, little endian, CRC-32C of the payload
+//	payload op byte followed by the item encoded by the Codec
+//
+// A crash in the middle of an append leaves a torn record at the end of the
+// log, possibly followed by zeros when the file grew before the data reached
+// the disk.  It is detected through its length or checksum and discarded when
+// the Tree is opened again.  An invalid record followed by a valid one can't
+// be the result of a crash, and makes Open fail with ErrCorrupted.  However,
+// a corrupted record which nothing valid follows, such as the last record of
+// the log or one whose length runs past the end of the log, can't be told
+// apart from a torn one, and is discarded along with the rest of the log.
+package durable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sylr.dev/btree/v2"
+)
+
+const (
+	walName      = "wal"
+	snapshotName = "snapshot"
+
+	// DefaultDegree is the degree of the B-Tree when Options.Degree is 0.
+	DefaultDegree = 32
+)
+
+// Record operations.
+const (
+	opEnd    byte = iota // ends a snapshot, followed by the number of items
+	opPut                // inserts or replaces an item
+	opDelete             // deletes an item
+)
+
+// headerSize is the size of the length and checksum preceding a payload.
+const headerSize = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCorrupted is returned by Open when the snapshot is unreadable, when a
+// record of the log followed by a valid one is unreadable, or when a record of
+// the log is valid but can't be applied.
+var ErrCorrupted = errors.New("durable: corrupted data")
+
+// Codec encodes items to and from their on-disk representation.
+type Codec[T any] interface {
+	Marshal(item T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// SyncPolicy tells when the log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs the log after every modification, which makes them
+	// durable as soon as they return.
+	SyncAlways SyncPolicy = iota
+	// SyncNever leaves flushing the log to the operating system, the last
+	// modifications may be lost on a system crash.  Sync forces a flush.
+	SyncNever
+)
+
+// Options configure a Tree.
+type Options struct {
+	// Degree is the degree of the in-memory B-Tree, DefaultDegree if 0.
+	Degree int
+	// Sync is the policy used to flush the log.
+	Sync SyncPolicy
+	// CheckpointEvery triggers a checkpoint once the log holds that many
+	// records.  0 disables automatic checkpoints.
+	CheckpointEvery int
+}
+
+// Tree is a B-Tree whose modifications are persisted to a directory.
+//
+// As for btree.BTree, write operations are not safe for concurrent use by
+// multiple goroutines.
+type Tree[T btree.Item[T]] struct {
+	dir     string
+	codec   Codec[T]
+	opts    Options
+	tree    *btree.BTree[T]
+	wal     *os.File
+	size    int64 // of the log
+	records int
+	buf     []byte
+}
+
+// Open loads the tree persisted in dir, creating dir if needed.
+func Open[T btree.Item[T]](dir string, codec Codec[T], opts Options) (*Tree[T], error) {
+	if opts.Degree == 0 {
+		opts.Degree = DefaultDegree
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("durable: %w", err)
+	}
+
+	t := &Tree[T]{
+		dir:   dir,
+		codec: codec,
+		opts:  opts,
+		tree:  btree.New[T](opts.Degree),
+	}
+	if err := t.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := t.replay(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// BTree returns the in-memory tree, which must only be used for reading:
+// modifications made to it directly are not persisted.
+func (t *Tree[T]) BTree() *btree.BTree[T] {
+	return t.tree
+}
+
+// Get looks for the key item in the tree, see btree.BTree.Get.
+func (t *Tree[T]) Get(key T) (T, bool) {
+	return t.tree.Get(key)
+}
+
+// Has returns true if the given key is in the tree.
+func (t *Tree[T]) Has(key T) bool {
+	return t.tree.Has(key)
+}
+
+// Len returns the number of items currently in the tree.
+func (t *Tree[T]) Len() int {
+	return t.tree.Len()
+}
+
+// ReplaceOrInsert logs then adds the given item to the tree, see
+// btree.BTree.ReplaceOrInsert.  The tree is left untouched if the item can't
+// be logged.
+func (t *Tree[T]) ReplaceOrInsert(item T) (_ T, _ bool, err error) {
+	if err = t.log(opPut, item); err != nil {
+		return
+	}
+	out, ok := t.tree.ReplaceOrInsert(item)
+	return out, ok, t.maybeCheckpoint()
+}
+
+// Delete logs then removes the item equal to the passed in item from the
+// tree, see btree.BTree.Delete.  The tree is left untouched if the deletion
+// can't be logged.
+func (t *Tree[T]) Delete(item T) (_ T, _ bool, err error) {
+	if err = t.log(opDelete, item); err != nil {
+		return
+	}
+	out, ok := t.tree.Delete(item)
+	return out, ok, t.maybeCheckpoint()
+}
+
+// Sync flushes the log to stable storage.
+func (t *Tree[T]) Sync() error {
+	if err := t.wal.Sync(); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	return nil
+}
+
+// Checkpoint writes the items of the tree to a new snapshot and empties the
+// log.
+//
+// The snapshot is written to a temporary file which atomically replaces the
+// previous one, so a crash during a checkpoint leaves the former snapshot and
+// log in place.  A crash between the replacement and the truncation of the
+// log is harmless too, since replaying the log on top of a snapshot which
+// already includes its records yields the same tree.
+func (t *Tree[T]) Checkpoint() error {
+	tmp := filepath.Join(t.dir, snapshotName+".tmp")
+	if err := t.writeSnapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(t.dir, snapshotName)); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	if err := syncDir(t.dir); err != nil {
+		return err
+	}
+	if err := t.wal.Truncate(0); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	// Truncate leaves the offset where it was, appends must start over from
+	// the beginning of the log.
+	if _, err := t.wal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	t.size, t.records = 0, 0
+
+	return t.Sync()
+}
+
+// Close flushes and closes the log.  The tree must not be modified
+// afterwards.
+func (t *Tree[T]) Close() error {
+	err := t.wal.Sync()
+	if cerr := t.wal.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	return nil
+}
+
+func (t *Tree[T]) maybeCheckpoint() error {
+	if t.opts.CheckpointEvery <= 0 || t.records < t.opts.CheckpointEvery {
+		return nil
+	}
+	return t.Checkpoint()
+}
+
+// log appends a record to the log.  If the record can't be written or
+// flushed, the log is truncated back to its previous size so that the failed
+// operation is neither replayed nor followed by unreadable data.
+func (t *Tree[T]) log(op byte, item T) error {
+	data, err := t.codec.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	t.buf = appendRecord(t.buf[:0], op, data)
+	_, err = t.wal.Write(t.buf)
+	if err == nil && t.opts.Sync == SyncAlways {
+		err = t.wal.Sync()
+	}
+	if err != nil {
+		if terr := t.rewind(); terr != nil {
+			return fmt.Errorf("durable: %w (rewinding the log: %v)", err, terr)
+		}
+		return fmt.Errorf("durable: %w", err)
+	}
+	t.size += int64(len(t.buf))
+	t.records++
+	return nil
+}
+
+// rewind truncates the log back to the end of its last logged record.
+func (t *Tree[T]) rewind() error {
+	if err := t.wal.Truncate(t.size); err != nil {
+		return err
+	}
+	_, err := t.wal.Seek(t.size, io.SeekStart)
+	return err
+}
+
+// replay applies the records of the log to the tree, then opens the log for
+// appending.  A torn tail, an invalid record which no valid record follows, is
+// truncated.
+func (t *Tree[T]) replay() error {
+	path := filepath.Join(t.dir, walName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("durable: %w", err)
+	}
+
+	var valid int64
+	r := bufio.NewReader(f)
+	for {
+		op, data, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// Only the last record may be the remains of an interrupted
+			// append: a valid record after it would be lost.
+			found, ferr := findRecord(f, valid+1, fi.Size())
+			if ferr != nil {
+				f.Close()
+				return fmt.Errorf("durable: %w", ferr)
+			}
+			if !found {
+				break
+			}
+			f.Close()
+			return fmt.Errorf("%w: log record at offset %d: %v", ErrCorrupted, valid, err)
+		}
+		item, err := t.codec.Unmarshal(data)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("%w: log record at offset %d: %v", ErrCorrupted, valid, err)
+		}
+		switch op {
+		case opPut:
+			t.tree.ReplaceOrInsert(item)
+		case opDelete:
+			t.tree.Delete(item)
+		default:
+			f.Close()
+			return fmt.Errorf("%w: log record at offset %d: unknown operation %d", ErrCorrupted, valid, op)
+		}
+		valid += n
+		t.records++
+	}
+
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return fmt.Errorf("durable: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("durable: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("durable: %w", err)
+	}
+	t.wal, t.size = f, valid
+
+	return syncDir(t.dir)
+}
+
+// loadSnapshot loads the items of the snapshot, if any, into the tree.
+func (t *Tree[T]) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(t.dir, snapshotName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	defer f.Close()
+
+	var batch []T
+	r := bufio.NewReader(f)
+	for {
+		op, data, _, err := readRecord(r)
+		if err != nil {
+			return fmt.Errorf("%w: snapshot: %v", ErrCorrupted, err)
+		}
+		if op == opEnd {
+			if len(data) != 8 || binary.LittleEndian.Uint64(data) != uint64(len(batch)) {
+				return fmt.Errorf("%w: snapshot: item count mismatch", ErrCorrupted)
+			}
+			break
+		}
+		if op != opPut {
+			return fmt.Errorf("%w: snapshot: unknown operation %d", ErrCorrupted, op)
+		}
+		item, err := t.codec.Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("%w: snapshot: %v", ErrCorrupted, err)
+		}
+		batch = append(batch, item)
+	}
+	t.tree.ReplaceOrInsertMany(batch)
+
+	return nil
+}
+
+// writeSnapshot writes the items of the tree, in order, to path.
+func (t *Tree[T]) writeSnapshot(path string) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("durable: %w", cerr)
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	var buf []byte
+	t.tree.Ascend(func(item T) bool {
+		var data []byte
+		if data, err = t.codec.Marshal(item); err != nil {
+			err = fmt.Errorf("durable: %w", err)
+			return false
+		}
+		buf = appendRecord(buf[:0], opPut, data)
+		if _, err = w.Write(buf); err != nil {
+			err = fmt.Errorf("durable: %w", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	count := binary.LittleEndian.AppendUint64(nil, uint64(t.tree.Len()))
+	if _, err := w.Write(appendRecord(buf[:0], opEnd, count)); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+
+	return nil
+}
+
+// appendRecord appends the framed record made of op and data to buf.
+func appendRecord(buf []byte, op byte, data []byte) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, headerSize)...)
+	buf = append(buf, op)
+	buf = append(buf, data...)
+	payload := buf[start+headerSize:]
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[start+4:], crc32.Checksum(payload, castagnoli))
+	return buf
+}
+
+// readRecord reads the next record from r, returning its operation, data and
+// total size.  It returns io.EOF at the end of r, io.ErrUnexpectedEOF if the
+// record is incomplete, and another error along with the size of the record
+// if it is empty or doesn't match its checksum.
+func readRecord(r io.Reader) (op byte, data []byte, n int64, err error) {
+	var header [headerSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	length := binary.LittleEndian.Uint32(header[:])
+	if length == 0 {
+		return 0, nil, headerSize, errors.New("empty record")
+	}
+	// Read through a LimitReader so that a garbage length doesn't make us
+	// allocate more than what is actually left.
+	payload, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return
+	}
+	if len(payload) != int(length) {
+		return 0, nil, 0, io.ErrUnexpectedEOF
+	}
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(header[4:]) {
+		return 0, nil, headerSize + int64(length), errors.New("checksum mismatch")
+	}
+	return payload[0], payload[1:], headerSize + int64(length), nil
+}
+
+// findRecord reports whether a valid record starts anywhere in f between off
+// and size.
+func findRecord(f io.ReaderAt, off, size int64) (bool, error) {
+	var header [headerSize]byte
+	var payload []byte
+	for ; off+headerSize < size; off++ {
+		if _, err := f.ReadAt(header[:], off); err != nil {
+			return false, err
+		}
+		length := int64(binary.LittleEndian.Uint32(header[:]))
+		if length == 0 || off+headerSize+length > size {
+			continue
+		}
+		if int64(cap(payload)) < length {
+			payload = make([]byte, length)
+		}
+		payload = payload[:length]
+		if _, err := f.ReadAt(payload, off+headerSize); err != nil {
+			return false, err
+		}
+		if crc32.Checksum(payload, castagnoli) == binary.LittleEndian.Uint32(header[4:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// syncDir flushes the entries of a directory to stable storage.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("durable: %w", err)
+	}
+	return nil
+}
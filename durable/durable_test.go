@@ -0,0 +1,285 @@
+//go:build !goexperiment.arenas
+
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testItem struct {
+	key   int64
+	value string
+}
+
+func (i testItem) Less(i2 testItem) bool {
+	return i.key < i2.key
+}
+
+func (i testItem) DeepCopy() testItem {
+	return i
+}
+
+type testCodec struct{}
+
+func (testCodec) Marshal(item testItem) ([]byte, error) {
+	return append(binary.LittleEndian.AppendUint64(nil, uint64(item.key)), item.value...), nil
+}
+
+func (testCodec) Unmarshal(data []byte) (testItem, error) {
+	if len(data) < 8 {
+		return testItem{}, errors.New("short item")
+	}
+	return testItem{key: int64(binary.LittleEndian.Uint64(data)), value: string(data[8:])}, nil
+}
+
+func items(tr *Tree[testItem]) (out []testItem) {
+	tr.BTree().Ascend(func(item testItem) bool {
+		out = append(out, item)
+		return true
+	})
+	return out
+}
+
+func open(t *testing.T, dir string, opts Options) *Tree[testItem] {
+	t.Helper()
+	tr, err := Open[testItem](dir, testCodec{}, opts)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return tr
+}
+
+func TestReopen(t *testing.T) {
+	for _, opts := range []Options{
+		{Degree: 2},
+		{Degree: 3, Sync: SyncNever},
+		{Degree: 4, CheckpointEvery: 50},
+	} {
+		dir := t.TempDir()
+		tr := open(t, dir, opts)
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 100; j++ {
+				k := int64(rand.Intn(200))
+				var err error
+				if rand.Intn(3) == 0 {
+					_, _, err = tr.Delete(testItem{key: k})
+				} else {
+					_, _, err = tr.ReplaceOrInsert(testItem{key: k, value: string(rune('a' + rand.Intn(26)))})
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if i == 2 {
+				if err := tr.Checkpoint(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := items(tr)
+			if err := tr.Close(); err != nil {
+				t.Fatal(err)
+			}
+			tr = open(t, dir, opts)
+			if got := items(tr); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%+v: reopened tree has %v items, want %v", opts, len(got), len(want))
+			}
+		}
+		tr.Close()
+	}
+}
+
+func TestCheckpointEvery(t *testing.T) {
+	dir := t.TempDir()
+	tr := open(t, dir, Options{CheckpointEvery: 10})
+	defer tr.Close()
+	for i := 0; i < 25; i++ {
+		if _, _, err := tr.ReplaceOrInsert(testItem{key: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tr.records != 5 {
+		t.Fatalf("log has %v records, want 5", tr.records)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotName)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteAfterCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	tr := open(t, dir, Options{})
+	for i := 0; i < 20; i++ {
+		if _, _, err := tr.ReplaceOrInsert(testItem{key: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 9 {
+			if err := tr.Checkpoint(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tr.Close()
+
+	tr = open(t, dir, Options{})
+	defer tr.Close()
+	if tr.Len() != 20 {
+		t.Fatalf("reopened tree has %v items, want 20", tr.Len())
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	for name, corrupt := range map[string]func(wal []byte) []byte{
+		"partial header":  func(wal []byte) []byte { return append(wal, 42, 0) },
+		"partial payload": func(wal []byte) []byte { return wal[:len(wal)-3] },
+		"checksum": func(wal []byte) []byte {
+			wal[len(wal)-1] ^= 0xff
+			return wal
+		},
+		"garbage length": func(wal []byte) []byte { return append(wal, 0xff, 0xff, 0xff, 0x7f, 0, 0, 0, 0, 1) },
+		// The file may grow before its new data reaches the disk.
+		"zeros": func(wal []byte) []byte { return append(wal, make([]byte, 4096)...) },
+		"partial payload and zeros": func(wal []byte) []byte {
+			return append(wal[:len(wal)-3], make([]byte, 4096)...)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			tr := open(t, dir, Options{})
+			for i := 0; i < 10; i++ {
+				tr.ReplaceOrInsert(testItem{key: int64(i), value: "value"})
+			}
+			tr.Close()
+
+			path := filepath.Join(dir, walName)
+			wal, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, corrupt(wal), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			tr = open(t, dir, Options{})
+			want := 10
+			if name == "partial payload" || name == "checksum" || name == "partial payload and zeros" {
+				want = 9
+			}
+			if tr.Len() != want {
+				t.Fatalf("recovered %v items, want %v", tr.Len(), want)
+			}
+
+			// Appends must go after the last valid record.
+			tr.ReplaceOrInsert(testItem{key: 100})
+			tr.Close()
+			tr = open(t, dir, Options{})
+			defer tr.Close()
+			if tr.Len() != want+1 || !tr.Has(testItem{key: 100}) {
+				t.Fatalf("got %v items after reopening, want %v", tr.Len(), want+1)
+			}
+		})
+	}
+}
+
+func TestCorruptedLog(t *testing.T) {
+	for name, corrupt := range map[string]func(wal []byte){
+		"checksum":     func(wal []byte) { wal[headerSize] ^= 0xff },
+		"empty record": func(wal []byte) { copy(wal, make([]byte, headerSize)) },
+		"length past the end": func(wal []byte) {
+			binary.LittleEndian.PutUint32(wal, 1<<30)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			tr := open(t, dir, Options{})
+			for i := 0; i < 10; i++ {
+				tr.ReplaceOrInsert(testItem{key: int64(i), value: "value"})
+			}
+			tr.Close()
+
+			path := filepath.Join(dir, walName)
+			wal, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			corrupt(wal)
+			if err := os.WriteFile(path, wal, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Open[testItem](dir, testCodec{}, Options{}); !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("open: got %v, want %v", err, ErrCorrupted)
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Size() != int64(len(wal)) {
+				t.Fatalf("corrupted log was truncated")
+			}
+		})
+	}
+}
+
+func TestRewind(t *testing.T) {
+	dir := t.TempDir()
+	tr := open(t, dir, Options{})
+	tr.ReplaceOrInsert(testItem{key: 1})
+	// Leave the remains of a failed append behind, as log does before
+	// rewinding.
+	if _, err := tr.wal.Write([]byte{0xff, 0xff, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.rewind(); err != nil {
+		t.Fatal(err)
+	}
+	tr.ReplaceOrInsert(testItem{key: 2})
+	tr.Close()
+
+	tr = open(t, dir, Options{})
+	defer tr.Close()
+	if tr.Len() != 2 {
+		t.Fatalf("reopened tree has %v items, want 2", tr.Len())
+	}
+}
+
+func TestCorruptedSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	tr := open(t, dir, Options{})
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(testItem{key: int64(i)})
+	}
+	if err := tr.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	tr.Close()
+
+	path := filepath.Join(dir, snapshotName)
+	snapshot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, snapshot[:len(snapshot)-1], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open[testItem](dir, testCodec{}, Options{}); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("open: got %v, want %v", err, ErrCorrupted)
+	}
+}
+
+func BenchmarkReplaceOrInsert(b *testing.B) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncNever} {
+		name := map[SyncPolicy]string{SyncAlways: "SyncAlways", SyncNever: "SyncNever"}[policy]
+		b.Run(name, func(b *testing.B) {
+			tr, err := Open[testItem](b.TempDir(), testCodec{}, Options{Sync: policy, CheckpointEvery: 10000})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer tr.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tr.ReplaceOrInsert(testItem{key: int64(i), value: "value"})
+			}
+		})
+	}
+}
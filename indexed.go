@@ -0,0 +1,213 @@
+package btree
+
+import "fmt"
+
+// indexEntry is an item of a secondary index, ordered by the index's
+// LessFunc then by the primary ordering.  Pivots set bound to come before
+// (-1) or after (+1) all the items the LessFunc considers equal to theirs.
+type indexEntry[T Item[T]] struct {
+	item  T
+	less  LessFunc[T]
+	bound int8
+}
+
+func (e indexEntry[T]) Less(e2 indexEntry[T]) bool {
+	switch {
+	case e.less(e.item, e2.item):
+		return true
+	case e.less(e2.item, e.item):
+		return false
+	case e.bound != 0 || e2.bound != 0:
+		return e.bound < e2.bound
+	}
+	return e.item.Less(e2.item)
+}
+
+func (e indexEntry[T]) DeepCopy() indexEntry[T] {
+	return indexEntry[T]{item: e.item.DeepCopy(), less: e.less, bound: e.bound}
+}
+
+// Indexed is a set of items ordered by their Item ordering, the primary one,
+// along with secondary indexes ordering the same items by other criteria.
+//
+// Items are unique according to the primary ordering only: any number of
+// items may be equal according to a secondary ordering.
+type Indexed[T Item[T]] struct {
+	primary *BTree[T]
+	indexes map[string]*Index[T]
+}
+
+// Index is a secondary index of an Indexed set, see Indexed.Index.
+type Index[T Item[T]] struct {
+	less LessFunc[T]
+	tree *BTree[indexEntry[T]]
+}
+
+// NewIndexed creates a new Indexed set whose trees have the given degree,
+// with a secondary index for every LessFunc of indexes.
+func NewIndexed[T Item[T]](degree int, indexes map[string]LessFunc[T]) *Indexed[T] {
+	t := &Indexed[T]{
+		primary: New[T](degree),
+		indexes: make(map[string]*Index[T], len(indexes)),
+	}
+	for name, less := range indexes {
+		t.indexes[name] = &Index[T]{less: less, tree: New[indexEntry[T]](degree)}
+	}
+
+	return t
+}
+
+// Index returns the secondary index with the given name.  It panics if there
+// is no such index.
+func (t *Indexed[T]) Index(name string) *Index[T] {
+	idx, ok := t.indexes[name]
+	if !ok {
+		panic(fmt.Sprintf("btree: unknown index %q", name))
+	}
+	return idx
+}
+
+// ReplaceOrInsert adds the given item to the set and all its indexes.  If an
+// item of the set already equals the given one according to the primary
+// ordering, it is replaced, in every index, and returned along with true.
+// Otherwise, (zeroValue, false) is returned.
+func (t *Indexed[T]) ReplaceOrInsert(item T) (T, bool) {
+	old, replaced := t.primary.ReplaceOrInsert(item)
+	for _, idx := range t.indexes {
+		if replaced {
+			idx.tree.Delete(indexEntry[T]{item: old, less: idx.less})
+		}
+		idx.tree.ReplaceOrInsert(indexEntry[T]{item: item, less: idx.less})
+	}
+
+	return old, replaced
+}
+
+// Delete removes the item equal to the passed in item, according to the
+// primary ordering, from the set and all its indexes, and returns it.  If no
+// such item exists, returns (zeroValue, false).
+func (t *Indexed[T]) Delete(key T) (T, bool) {
+	old, ok := t.primary.Delete(key)
+	if ok {
+		for _, idx := range t.indexes {
+			idx.tree.Delete(indexEntry[T]{item: old, less: idx.less})
+		}
+	}
+
+	return old, ok
+}
+
+// Get looks for the key item in the set, according to the primary ordering,
+// returning it.  It returns (zeroValue, false) if unable to find that item.
+func (t *Indexed[T]) Get(key T) (T, bool) {
+	return t.primary.Get(key)
+}
+
+// Has returns true if the given key is in the set.
+func (t *Indexed[T]) Has(key T) bool {
+	return t.primary.Has(key)
+}
+
+// Len returns the number of items in the set.
+func (t *Indexed[T]) Len() int {
+	return t.primary.Len()
+}
+
+// Ascend calls the iterator for every item of the set, in ascending primary
+// order, until iterator returns false.
+func (t *Indexed[T]) Ascend(iterator ItemIterator[T]) {
+	t.primary.Ascend(iterator)
+}
+
+// AscendRange calls the iterator for every item of the set within the range
+// [greaterOrEqual, lessThan) of the primary ordering, until iterator returns
+// false.
+func (t *Indexed[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIterator[T]) {
+	t.primary.AscendRange(greaterOrEqual, lessThan, iterator)
+}
+
+// pivot returns an entry placed before (bound < 0) or after (bound > 0) the
+// items equal to key.
+func (idx *Index[T]) pivot(key T, bound int8) indexEntry[T] {
+	return indexEntry[T]{item: key, less: idx.less, bound: bound}
+}
+
+func (idx *Index[T]) iterate(lo, hi Bound[indexEntry[T]], dir Direction, iterator ItemIterator[T]) {
+	idx.tree.Range(lo, hi, dir).Each(func(e indexEntry[T]) bool {
+		return iterator(e.item)
+	})
+}
+
+// Equal calls the iterator for every item equal to key according to the
+// index, in ascending primary order, until iterator returns false.
+func (idx *Index[T]) Equal(key T, iterator ItemIterator[T]) {
+	idx.iterate(Bound[indexEntry[T]]{Item: idx.pivot(key, -1)}, Bound[indexEntry[T]]{Item: idx.pivot(key, 1)}, Ascending, iterator)
+}
+
+// AscendRange calls the iterator for every item within the range
+// [greaterOrEqual, lessThan) of the index, in ascending order, until iterator
+// returns false.
+func (idx *Index[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIterator[T]) {
+	idx.iterate(Bound[indexEntry[T]]{Item: idx.pivot(greaterOrEqual, -1)}, Bound[indexEntry[T]]{Item: idx.pivot(lessThan, -1)}, Ascending, iterator)
+}
+
+// AscendLessThan calls the iterator for every item within the range
+// [first, pivot) of the index, in ascending order, until iterator returns
+// false.
+func (idx *Index[T]) AscendLessThan(pivot T, iterator ItemIterator[T]) {
+	idx.iterate(Unbounded[indexEntry[T]](), Bound[indexEntry[T]]{Item: idx.pivot(pivot, -1)}, Ascending, iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator for every item within the range
+// [pivot, last] of the index, in ascending order, until iterator returns
+// false.
+func (idx *Index[T]) AscendGreaterOrEqual(pivot T, iterator ItemIterator[T]) {
+	idx.iterate(Bound[indexEntry[T]]{Item: idx.pivot(pivot, -1)}, Unbounded[indexEntry[T]](), Ascending, iterator)
+}
+
+// Ascend calls the iterator for every item of the index, in ascending order,
+// until iterator returns false.
+func (idx *Index[T]) Ascend(iterator ItemIterator[T]) {
+	idx.iterate(Unbounded[indexEntry[T]](), Unbounded[indexEntry[T]](), Ascending, iterator)
+}
+
+// DescendRange calls the iterator for every item within the range
+// [lessOrEqual, greaterThan) of the index, in descending order, until
+// iterator returns false.
+func (idx *Index[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIterator[T]) {
+	idx.iterate(Bound[indexEntry[T]]{Item: idx.pivot(greaterThan, 1)}, Bound[indexEntry[T]]{Item: idx.pivot(lessOrEqual, 1)}, Descending, iterator)
+}
+
+// DescendLessOrEqual calls the iterator for every item within the range
+// [pivot, first] of the index, in descending order, until iterator returns
+// false.
+func (idx *Index[T]) DescendLessOrEqual(pivot T, iterator ItemIterator[T]) {
+	idx.iterate(Unbounded[indexEntry[T]](), Bound[indexEntry[T]]{Item: idx.pivot(pivot, 1)}, Descending, iterator)
+}
+
+// DescendGreaterThan calls the iterator for every item within the range
+// [last, pivot) of the index, in descending order, until iterator returns
+// false.
+func (idx *Index[T]) DescendGreaterThan(pivot T, iterator ItemIterator[T]) {
+	idx.iterate(Bound[indexEntry[T]]{Item: idx.pivot(pivot, 1)}, Unbounded[indexEntry[T]](), Descending, iterator)
+}
+
+// Descend calls the iterator for every item of the index, in descending
+// order, until iterator returns false.
+func (idx *Index[T]) Descend(iterator ItemIterator[T]) {
+	idx.iterate(Unbounded[indexEntry[T]](), Unbounded[indexEntry[T]](), Descending, iterator)
+}
+
+// Min returns the lowest item of the index, or (zeroValue, false) if the set
+// is empty.
+func (idx *Index[T]) Min() (T, bool) {
+	e, ok := idx.tree.Min()
+	return e.item, ok
+}
+
+// Max returns the highest item of the index, or (zeroValue, false) if the set
+// is empty.
+func (idx *Index[T]) Max() (T, bool) {
+	e, ok := idx.tree.Max()
+	return e.item, ok
+}
@@ -0,0 +1,9 @@
+//go:build goexperiment.arenas
+
+package btree
+
+import "arena"
+
+func (e indexEntry[T]) DeepCopyWithArena(a *arena.Arena) indexEntry[T] {
+	return indexEntry[T]{item: e.item.DeepCopyWithArena(a), less: e.less, bound: e.bound}
+}
@@ -0,0 +1,114 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type testRecord struct {
+	id, ts int
+	name   string
+}
+
+func (r *testRecord) Less(r2 *testRecord) bool { return r.id < r2.id }
+
+func (r *testRecord) DeepCopy() *testRecord {
+	r2 := *r
+	return &r2
+}
+
+func byTS(a, b *testRecord) bool   { return a.ts < b.ts }
+func byName(a, b *testRecord) bool { return a.name < b.name }
+
+func TestIndexed(t *testing.T) {
+	tr := NewIndexed[*testRecord](*btreeDegree, map[string]LessFunc[*testRecord]{
+		"ts":   byTS,
+		"name": byName,
+	})
+	model := map[int]*testRecord{}
+	names := []string{"a", "b", "c", "d", "e"}
+
+	for i := 0; i < 3000; i++ {
+		id := rand.Intn(300)
+		switch rand.Intn(3) {
+		case 0, 1:
+			r := &testRecord{id: id, ts: rand.Intn(100), name: names[rand.Intn(len(names))]}
+			old, replaced := tr.ReplaceOrInsert(r)
+			if want, ok := model[id]; replaced != ok || ok && old != want {
+				t.Fatalf("replace %v: got %v, %v", id, old, replaced)
+			}
+			model[id] = r
+		case 2:
+			old, ok := tr.Delete(&testRecord{id: id})
+			if want, wok := model[id]; ok != wok || ok && old != want {
+				t.Fatalf("delete %v: got %v, %v", id, old, ok)
+			}
+			delete(model, id)
+		}
+	}
+
+	all := make([]*testRecord, 0, len(model))
+	for _, r := range model {
+		all = append(all, r)
+	}
+	collect := func(each func(ItemIterator[*testRecord])) (out []*testRecord) {
+		each(func(r *testRecord) bool {
+			out = append(out, r)
+			return true
+		})
+		return out
+	}
+	filter := func(less LessFunc[*testRecord], keep func(r *testRecord) bool) (out []*testRecord) {
+		sort.Slice(all, func(i, j int) bool {
+			return less(all[i], all[j]) || !less(all[j], all[i]) && all[i].id < all[j].id
+		})
+		for _, r := range all {
+			if keep(r) {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+
+	for name, less := range map[string]LessFunc[*testRecord]{"ts": byTS, "name": byName} {
+		idx := tr.Index(name)
+		want := filter(less, func(*testRecord) bool { return true })
+		if got := collect(idx.Ascend); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: ascend\n got: %v\nwant: %v", name, got, want)
+		}
+		if min, _ := idx.Min(); min != want[0] {
+			t.Fatalf("%v: min %v, want %v", name, min, want[0])
+		}
+
+		for i := 0; i < 50; i++ {
+			lo := &testRecord{ts: rand.Intn(100), name: names[rand.Intn(len(names))]}
+			hi := &testRecord{ts: rand.Intn(100), name: names[rand.Intn(len(names))]}
+			want := filter(less, func(r *testRecord) bool { return !less(r, lo) && less(r, hi) })
+			if got := collect(func(it ItemIterator[*testRecord]) { idx.AscendRange(lo, hi, it) }); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v: ascend range [%+v, %+v)\n got: %v\nwant: %v", name, lo, hi, got, want)
+			}
+			want = filter(less, func(r *testRecord) bool { return !less(r, lo) && !less(lo, r) })
+			if got := collect(func(it ItemIterator[*testRecord]) { idx.Equal(lo, it) }); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v: equal %+v\n got: %v\nwant: %v", name, lo, got, want)
+			}
+			want = filter(less, func(r *testRecord) bool { return !less(hi, r) && less(lo, r) })
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+			if got := collect(func(it ItemIterator[*testRecord]) { idx.DescendRange(hi, lo, it) }); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v: descend range [%+v, %+v)\n got: %v\nwant: %v", name, hi, lo, got, want)
+			}
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unknown index didn't panic")
+		}
+	}()
+	tr.Index("unknown")
+}
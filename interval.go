@@ -0,0 +1,90 @@
+package btree
+
+// Interval is implemented by the items of an IntervalTree.
+//
+// Intervals are closed, they span from Start() to End() included, and Less
+// must order them by their Start() first.
+type Interval[T any, P any] interface {
+	Item[T]
+	Start() P
+	End() P
+}
+
+// IntervalTree is a BTree of intervals in which every subtree keeps track of
+// the highest end point of its intervals, so that the intervals overlapping a
+// given range can be found in O(log n + k) where k is the number of matches.
+type IntervalTree[T Interval[T, P], P any] struct {
+	*AugmentedBTree[T, P]
+	less func(a, b P) bool
+}
+
+// maxEnd is the Augmenter of IntervalTree.
+type maxEnd[T Interval[T, P], P any] func(a, b P) bool
+
+func (less maxEnd[T, P]) Summarize(item T) P {
+	return item.End()
+}
+
+func (less maxEnd[T, P]) Combine(a, b P) P {
+	if less(a, b) {
+		return b
+	}
+	return a
+}
+
+// NewInterval creates a new interval tree with the given degree.  less
+// orders the end points of the intervals.
+func NewInterval[T Interval[T, P], P any](degree int, less func(a, b P) bool) *IntervalTree[T, P] {
+	return &IntervalTree[T, P]{
+		AugmentedBTree: NewAugmented[T, P](degree, maxEnd[T, P](less)),
+		less:           less,
+	}
+}
+
+// Overlapping calls the iterator, in order, for every interval of the tree
+// which overlaps [lo, hi], until iterator returns false.
+func (t *IntervalTree[T, P]) Overlapping(lo, hi P, iterator ItemIterator[T]) {
+	if t.root == nil {
+		return
+	}
+	t.overlapping(t.root, lo, hi, iterator)
+}
+
+// Containing calls the iterator, in order, for every interval of the tree
+// which contains point, until iterator returns false.
+func (t *IntervalTree[T, P]) Containing(point P, iterator ItemIterator[T]) {
+	t.Overlapping(point, point, iterator)
+}
+
+// Stabbing returns the intervals of the tree which contain point, in order.
+func (t *IntervalTree[T, P]) Stabbing(point P) (out []T) {
+	t.Containing(point, func(item T) bool {
+		out = append(out, item)
+		return true
+	})
+	return out
+}
+
+// overlapping visits the intervals of the subtree which overlap [lo, hi].
+// Subtrees whose intervals all end before lo are skipped, and the walk stops
+// at the first interval which starts after hi.
+func (t *IntervalTree[T, P]) overlapping(n *node[T], lo, hi P, iterator ItemIterator[T]) bool {
+	if end, ok := n.summary.(P); !ok || t.less(end, lo) {
+		return true
+	}
+	for i, item := range n.items {
+		if len(n.children) > 0 && !t.overlapping(n.children[i], lo, hi, iterator) {
+			return false
+		}
+		if t.less(hi, item.Start()) {
+			return false
+		}
+		if !t.less(item.End(), lo) && !iterator(item) {
+			return false
+		}
+	}
+	if len(n.children) > 0 {
+		return t.overlapping(n.children[len(n.children)-1], lo, hi, iterator)
+	}
+	return true
+}
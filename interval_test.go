@@ -0,0 +1,86 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type testInterval struct {
+	start, end int
+}
+
+func (i *testInterval) Less(i2 *testInterval) bool {
+	return i.start < i2.start || i.start == i2.start && i.end < i2.end
+}
+
+func (i *testInterval) DeepCopy() *testInterval {
+	i2 := *i
+	return &i2
+}
+
+func (i *testInterval) Start() int { return i.start }
+func (i *testInterval) End() int   { return i.end }
+
+func lessInt(a, b int) bool { return a < b }
+
+func TestIntervalTree(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		tr := NewInterval[*testInterval](degree, lessInt)
+		var all []*testInterval
+		for i := 0; i < 500; i++ {
+			start := rand.Intn(1000)
+			item := &testInterval{start: start, end: start + rand.Intn(100)}
+			if _, ok := tr.ReplaceOrInsert(item); !ok {
+				all = append(all, item)
+			}
+		}
+		for _, item := range all[:100] {
+			tr.Delete(item)
+		}
+		all = tr.Range(Unbounded[*testInterval](), Unbounded[*testInterval](), Ascending).Items()
+
+		for i := 0; i < 200; i++ {
+			lo := rand.Intn(1200) - 100
+			hi := lo + rand.Intn(50)
+			var want []*testInterval
+			for _, item := range all {
+				if item.start <= hi && item.end >= lo {
+					want = append(want, item)
+				}
+			}
+			var got []*testInterval
+			tr.Overlapping(lo, hi, func(item *testInterval) bool {
+				got = append(got, item)
+				return true
+			})
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v: overlapping [%v, %v]:\n got: %v\nwant: %v", degree, lo, hi, got, want)
+			}
+
+			want = want[:0]
+			for _, item := range all {
+				if item.start <= lo && item.end >= lo {
+					want = append(want, item)
+				}
+			}
+			if got := tr.Stabbing(lo); len(got) != len(want) || len(got) > 0 && !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v: stabbing %v:\n got: %v\nwant: %v", degree, lo, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkIntervalTreeStabbing(b *testing.B) {
+	tr := NewInterval[*testInterval](*btreeDegree, lessInt)
+	for i := 0; i < benchmarkTreeSize; i++ {
+		start := rand.Intn(benchmarkTreeSize * 10)
+		tr.ReplaceOrInsert(&testInterval{start: start, end: start + rand.Intn(100)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Containing(i%(benchmarkTreeSize*10), func(*testInterval) bool { return true })
+	}
+}
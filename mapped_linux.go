@@ -0,0 +1,261 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+)
+
+// MappedTree is a read-only B-Tree of encoded items served straight from a
+// memory-mapped snapshot written by WriteSnapshot.
+//
+// Items returned by a MappedTree point into the mapping: they must not be
+// modified, and must not be used once the tree is closed.  A MappedTree is
+// safe for concurrent use by multiple goroutines until it is closed.
+type MappedTree struct {
+	data    []byte
+	root    uint64
+	length  int
+	compare func(a, b []byte) int
+}
+
+// OpenMapped memory-maps the snapshot at path.  compare returns a negative
+// number, zero or a positive number when a is respectively lower than, equal
+// to or greater than b, and must order encoded items as they were ordered in
+// the snapshotted tree.
+//
+// The layout of every node is checked, which reads the whole snapshot once,
+// so that a truncated or corrupted file is reported here rather than making
+// lookups panic.
+func OpenMapped(path string, compare func(a, b []byte) int) (*MappedTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size < SnapshotPageSize || size%SnapshotPageSize != 0 || size != int64(int(size)) {
+		return nil, errors.New("btree: not a snapshot")
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("btree: mmap %s: %w", path, err)
+	}
+	m := &MappedTree{
+		data:    data,
+		compare: compare,
+	}
+	if err := m.check(); err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// check parses the footer then checks the nodes reachable from the root.
+// Children preceding their parent, the walk can't loop, and counting the items
+// along the way stops it from going through shared nodes over and over.
+func (m *MappedTree) check() error {
+	footer, err := parseSnapshotFooter(m.data[len(m.data)-SnapshotPageSize:])
+	if err != nil {
+		return err
+	}
+	m.root, m.length = footer.root, int(footer.length)
+	if m.root == snapshotNoRoot {
+		if m.length != 0 {
+			return errors.New("btree: corrupted snapshot: item count mismatch")
+		}
+		return nil
+	}
+
+	pages := uint64(len(m.data)/SnapshotPageSize - 1)
+	if m.root >= pages || footer.length != uint64(m.length) {
+		return errors.New("btree: corrupted snapshot")
+	}
+	var count uint64
+	stack := []uint64{m.root}
+	for len(stack) > 0 {
+		page := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := m.node(page)
+		if err := n.check(page); err != nil {
+			return err
+		}
+		if count += uint64(n.count()); count > footer.length {
+			return errors.New("btree: corrupted snapshot: item count mismatch")
+		}
+		for i := 0; !n.leaf() && i <= n.count(); i++ {
+			stack = append(stack, n.child(i))
+		}
+	}
+	if count != footer.length {
+		return errors.New("btree: corrupted snapshot: item count mismatch")
+	}
+	return nil
+}
+
+// Close unmaps the snapshot.
+func (m *MappedTree) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := syscall.Munmap(m.data)
+	m.data = nil
+	return err
+}
+
+// Len returns the number of items in the tree.
+func (m *MappedTree) Len() int {
+	return m.length
+}
+
+// node returns the node at the given page, which extends at most up to the
+// footer.
+func (m *MappedTree) node(page uint64) snapshotNode {
+	return snapshotNode(m.data[page*SnapshotPageSize : len(m.data)-SnapshotPageSize])
+}
+
+// search returns the index of the first item of n which is not lower than
+// key, and whether it is equal to key.
+func (m *MappedTree) search(n snapshotNode, key []byte) (int, bool) {
+	i := sort.Search(n.count(), func(i int) bool {
+		return m.compare(n.item(i), key) >= 0
+	})
+	return i, i < n.count() && m.compare(n.item(i), key) == 0
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (nil, false) if unable to find that item.
+func (m *MappedTree) Get(key []byte) ([]byte, bool) {
+	if m.root == snapshotNoRoot {
+		return nil, false
+	}
+	for n := m.node(m.root); ; {
+		i, found := m.search(n, key)
+		if found {
+			return n.item(i), true
+		}
+		if n.leaf() {
+			return nil, false
+		}
+		n = m.node(n.child(i))
+	}
+}
+
+// Has returns true if the given key is in the tree.
+func (m *MappedTree) Has(key []byte) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Ascend calls the iterator for every item in the tree, in ascending order,
+// until iterator returns false.
+func (m *MappedTree) Ascend(iterator func(item []byte) bool) {
+	m.AscendRange(nil, nil, iterator)
+}
+
+// AscendRange calls the iterator for every item within the range
+// [greaterOrEqual, lessThan), in ascending order, until iterator returns
+// false.  A nil bound leaves its side of the range open.
+func (m *MappedTree) AscendRange(greaterOrEqual, lessThan []byte, iterator func(item []byte) bool) {
+	if m.root != snapshotNoRoot {
+		m.ascend(m.root, greaterOrEqual, lessThan, iterator)
+	}
+}
+
+// Descend calls the iterator for every item in the tree, in descending order,
+// until iterator returns false.
+func (m *MappedTree) Descend(iterator func(item []byte) bool) {
+	m.DescendRange(nil, nil, iterator)
+}
+
+// DescendRange calls the iterator for every item within the range
+// [lessOrEqual, greaterThan), in descending order, until iterator returns
+// false.  A nil bound leaves its side of the range open.
+func (m *MappedTree) DescendRange(lessOrEqual, greaterThan []byte, iterator func(item []byte) bool) {
+	if m.root != snapshotNoRoot {
+		m.descend(m.root, lessOrEqual, greaterThan, iterator)
+	}
+}
+
+// Floor returns the greatest item of the tree lower than or equal to key, or
+// (nil, false) if there is none.
+func (m *MappedTree) Floor(key []byte) (out []byte, ok bool) {
+	m.DescendRange(key, nil, func(item []byte) bool {
+		out, ok = item, true
+		return false
+	})
+	return
+}
+
+// Ceil returns the lowest item of the tree greater than or equal to key, or
+// (nil, false) if there is none.
+func (m *MappedTree) Ceil(key []byte) (out []byte, ok bool) {
+	m.AscendRange(key, nil, func(item []byte) bool {
+		out, ok = item, true
+		return false
+	})
+	return
+}
+
+func (m *MappedTree) ascend(page uint64, lo, hi []byte, iterator func(item []byte) bool) bool {
+	n := m.node(page)
+	start := 0
+	if lo != nil {
+		start, _ = m.search(n, lo)
+	}
+	for i := start; i < n.count(); i++ {
+		if !n.leaf() && !m.ascend(n.child(i), lo, hi, iterator) {
+			return false
+		}
+		// Everything past the first child is above lo.
+		lo = nil
+		item := n.item(i)
+		if hi != nil && m.compare(item, hi) >= 0 {
+			return false
+		}
+		if !iterator(item) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return m.ascend(n.child(n.count()), lo, hi, iterator)
+	}
+	return true
+}
+
+func (m *MappedTree) descend(page uint64, hi, lo []byte, iterator func(item []byte) bool) bool {
+	n := m.node(page)
+	end, found := n.count(), false
+	if hi != nil {
+		end, found = m.search(n, hi)
+	}
+	if found {
+		// The child following hi only holds greater items.
+		end++
+	} else if !n.leaf() && !m.descend(n.child(end), hi, lo, iterator) {
+		return false
+	}
+	for i := end - 1; i >= 0; i-- {
+		item := n.item(i)
+		if lo != nil && m.compare(item, lo) <= 0 {
+			return false
+		}
+		if !iterator(item) {
+			return false
+		}
+		if !n.leaf() && !m.descend(n.child(i), nil, lo, iterator) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package btree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// encodeTestInt encodes an int so that encoded ints sort as their values.
+func encodeTestInt(i *testInt) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, uint64(*i)^1<<63), nil
+}
+
+func decodeTestInt(b []byte) int {
+	return int(binary.BigEndian.Uint64(b) ^ 1<<63)
+}
+
+func compareTestInt(a, b []byte) int {
+	x, y := decodeTestInt(a), decodeTestInt(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func writeMapped(t testing.TB, tr *BTree[*testInt]) *MappedTree {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "snapshot")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSnapshot(f, tr, encodeTestInt); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenMapped(path, compareTestInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMappedTree(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		for _, size := range []int{0, 1, 1000} {
+			tr := New[*testInt](degree)
+			for _, v := range rand.Perm(size * 2)[:size] {
+				tr.ReplaceOrInsert(newTestInt(v - size/2))
+			}
+			m := writeMapped(t, tr)
+
+			if m.Len() != tr.Len() {
+				t.Fatalf("degree %v: len %v, want %v", degree, m.Len(), tr.Len())
+			}
+			collect := func(each func(func([]byte) bool)) (out []int) {
+				each(func(item []byte) bool {
+					out = append(out, decodeTestInt(item))
+					return true
+				})
+				return out
+			}
+			want := collect(func(f func([]byte) bool) {
+				tr.Ascend(func(item *testInt) bool {
+					b, _ := encodeTestInt(item)
+					return f(b)
+				})
+			})
+			if got := collect(m.Ascend); !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v: ascend %v, want %v", degree, got, want)
+			}
+
+			for i := 0; i < 200; i++ {
+				key := newTestInt(rand.Intn(size*2+2) - size/2 - 1)
+				k, _ := encodeTestInt(key)
+				if got, ok := m.Get(k); ok != tr.Has(key) || ok && decodeTestInt(got) != int(*key) {
+					t.Fatalf("degree %v: get %v: got %v, %v", degree, *key, got, ok)
+				}
+				for _, c := range []struct {
+					name   string
+					mapped func([]byte) ([]byte, bool)
+					tree   func(*testInt) (*testInt, bool)
+				}{
+					{"floor", m.Floor, tr.Floor},
+					{"ceil", m.Ceil, tr.Ceil},
+				} {
+					got, ok := c.mapped(k)
+					want, wok := c.tree(key)
+					if ok != wok || ok && decodeTestInt(got) != int(*want) {
+						t.Fatalf("degree %v: %v %v: got %v, %v, want %v, %v", degree, c.name, *key, got, ok, want, wok)
+					}
+				}
+
+				hi := newTestInt(int(*key) + rand.Intn(size/4+1))
+				h, _ := encodeTestInt(hi)
+				var want []int
+				tr.AscendRange(key, hi, func(item *testInt) bool {
+					want = append(want, int(*item))
+					return true
+				})
+				if got := collect(func(f func([]byte) bool) { m.AscendRange(k, h, f) }); !reflect.DeepEqual(got, want) {
+					t.Fatalf("degree %v: ascend range [%v, %v): got %v, want %v", degree, *key, *hi, got, want)
+				}
+				want = nil
+				tr.DescendRange(hi, key, func(item *testInt) bool {
+					want = append(want, int(*item))
+					return true
+				})
+				if got := collect(func(f func([]byte) bool) { m.DescendRange(h, k, f) }); !reflect.DeepEqual(got, want) {
+					t.Fatalf("degree %v: descend range [%v, %v): got %v, want %v", degree, *hi, *key, got, want)
+				}
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMappedCorrupted(t *testing.T) {
+	tr := New[*testInt](3)
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, tr, encodeTestInt); err != nil {
+		t.Fatal(err)
+	}
+	footer, err := parseSnapshotFooter(buf.Bytes()[buf.Len()-SnapshotPageSize:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := int(footer.root) * SnapshotPageSize
+
+	for name, corrupt := range map[string]func(b []byte) []byte{
+		"child page": func(b []byte) []byte {
+			binary.LittleEndian.PutUint64(b[root+snapshotNodeHeaderSize:], 1<<40)
+			return b
+		},
+		"item offset": func(b []byte) []byte {
+			count := int(binary.LittleEndian.Uint32(b[root+4:]))
+			binary.LittleEndian.PutUint32(b[root+snapshotNodeHeaderSize+8*(count+1)+4:], 1<<30)
+			return b
+		},
+		"item count": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[root+4:], 1<<30)
+			return b
+		},
+		"missing page": func(b []byte) []byte { return b[SnapshotPageSize:] },
+	} {
+		b := corrupt(append([]byte(nil), buf.Bytes()...))
+		path := filepath.Join(t.TempDir(), "snapshot")
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if m, err := OpenMapped(path, compareTestInt); err == nil {
+			m.Close()
+			t.Fatalf("%v: corrupted snapshot was opened", name)
+		}
+	}
+}
+
+func BenchmarkMappedGet(b *testing.B) {
+	tr := New[*testInt](*btreeDegree)
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	m := writeMapped(b, tr)
+	defer m.Close()
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i], _ = encodeTestInt(newTestInt(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,106 @@
+package btree
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// MerkleBTree is a BTree whose nodes cache a hash of their subtree, which
+// gives the hash of the whole tree, or of any range of items, in O(log n)
+// once computed.
+//
+// The hash of a set of items is the sum, modulo 2^256, of the hashes of its
+// items.  Unlike a hash chaining the items in order, it doesn't depend on the
+// shape of the tree, so trees holding the same items hash the same whatever
+// their degree or the order in which items were inserted.  Replicas can thus
+// find where they differ by comparing RangeHash over ranges which they split
+// in halves until the differing items are isolated.
+//
+// RootHash and RangeHash are not collision-resistant against adversarial
+// items, even with a cryptographic hash function: finding sets of items whose
+// hashes sum to the same value is feasible with the generalized birthday
+// attack.  They detect accidental divergence between replicas, not tampering
+// by a party which chooses the items.
+//
+// Modifications of the tree only invalidate the hashes cached along the path
+// they went through, hashes are recomputed by the next call to RootHash or
+// RangeHash.  As a consequence, these methods modify the tree and must not
+// be called concurrently with other methods.
+//
+// Copies of a MerkleBTree made with DeepCopy and the like are plain BTrees.
+type MerkleBTree[T Item[T]] struct {
+	*BTree[T]
+	hash func(T) [32]byte
+}
+
+// merkleHash is the hash of a subtree cached in its root node.
+type merkleHash [32]byte
+
+// NewMerkle creates a new B-Tree with the given degree which hashes its items
+// with hash.
+func NewMerkle[T Item[T]](degree int, hash func(T) [32]byte) *MerkleBTree[T] {
+	t := &MerkleBTree[T]{
+		BTree: New[T](degree),
+		hash:  hash,
+	}
+	t.BTree.aug = t
+
+	return t
+}
+
+// update invalidates the hash of a modified node.
+func (t *MerkleBTree[T]) update(n *node[T]) {
+	n.summary = nil
+}
+
+// addHash adds b to a, both being 256 bits little endian integers.
+func addHash(a *[32]byte, b [32]byte) {
+	var carry uint64
+	for i := 0; i < 32; i += 8 {
+		x := binary.LittleEndian.Uint64(a[i:])
+		y := binary.LittleEndian.Uint64(b[i:])
+		var sum uint64
+		sum, carry = bits.Add64(x, y, carry)
+		binary.LittleEndian.PutUint64(a[i:], sum)
+	}
+}
+
+// nodeHash returns the hash of the subtree rooted at n, computing the hashes
+// which have been invalidated.
+func (t *MerkleBTree[T]) nodeHash(n *node[T]) [32]byte {
+	if h, ok := n.summary.(merkleHash); ok {
+		return h
+	}
+	var h [32]byte
+	for _, item := range n.items {
+		addHash(&h, t.hash(item))
+	}
+	for _, child := range n.children {
+		addHash(&h, t.nodeHash(child))
+	}
+	n.summary = merkleHash(h)
+
+	return h
+}
+
+// RootHash returns the hash of all the items of the tree, which is zero for
+// an empty tree.
+func (t *MerkleBTree[T]) RootHash() (h [32]byte) {
+	if t.root != nil {
+		h = t.nodeHash(t.root)
+	}
+	return h
+}
+
+// RangeHash returns the hash of the items of the tree within the range
+// between lo and hi.
+func (t *MerkleBTree[T]) RangeHash(lo, hi Bound[T]) (h [32]byte) {
+	if t.root != nil {
+		t.root.walkRange(lo, hi, func(n *node[T]) {
+			addHash(&h, t.nodeHash(n))
+		}, func(item T) {
+			addHash(&h, t.hash(item))
+		})
+	}
+	return h
+}
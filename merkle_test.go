@@ -0,0 +1,135 @@
+package btree
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func hashTestInt(i *testInt) [32]byte {
+	return sha256.Sum256(binary.LittleEndian.AppendUint64(nil, uint64(*i)))
+}
+
+// sumHashes returns the hash of items computed from scratch.
+func sumHashes(items []*testInt) (h [32]byte) {
+	for _, item := range items {
+		addHash(&h, hashTestInt(item))
+	}
+	return h
+}
+
+func TestAddHash(t *testing.T) {
+	a := [32]byte{0: 0xff, 1: 0xff, 2: 0xff, 3: 0xff, 4: 0xff, 5: 0xff, 6: 0xff, 7: 0xff, 8: 0xff, 31: 0x80}
+	addHash(&a, [32]byte{0: 1, 31: 0x80})
+	if want := ([32]byte{9: 1}); a != want {
+		t.Fatalf("got %x, want %x", a, want)
+	}
+}
+
+func TestMerkleBTree(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		tr := NewMerkle[*testInt](degree, hashTestInt)
+		for i := 0; i < 2000; i++ {
+			switch v := rand.Intn(500); rand.Intn(6) {
+			case 0, 1, 2:
+				tr.ReplaceOrInsert(newTestInt(v))
+			case 3:
+				tr.Delete(newTestInt(v))
+			case 4:
+				var keys []*testInt
+				for _, v := range rand.Perm(500)[:rand.Intn(50)] {
+					keys = append(keys, newTestInt(v))
+				}
+				tr.DeleteMany(keys)
+			case 5:
+				tr.Upsert(newTestInt(v), func(*testInt, bool) (*testInt, UpsertAction) {
+					return newTestInt(v), UpsertDelete
+				})
+			}
+			if rand.Intn(10) == 0 {
+				all := tr.Range(Unbounded[*testInt](), Unbounded[*testInt](), Ascending).Items()
+				if got, want := tr.RootHash(), sumHashes(all); got != want {
+					t.Fatalf("degree %v: root hash %x, want %x", degree, got, want)
+				}
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			lo := Bound[*testInt]{Item: newTestInt(rand.Intn(520) - 10), Inclusive: rand.Intn(2) == 0, Unbounded: rand.Intn(10) == 0}
+			hi := Bound[*testInt]{Item: newTestInt(rand.Intn(520) - 10), Inclusive: rand.Intn(2) == 0, Unbounded: rand.Intn(10) == 0}
+			want := sumHashes(tr.Range(lo, hi, Ascending).Items())
+			if got := tr.RangeHash(lo, hi); got != want {
+				t.Fatalf("degree %v: range hash(%+v, %+v) %x, want %x", degree, lo, hi, got, want)
+			}
+		}
+	}
+}
+
+func TestMerkleShapeIndependence(t *testing.T) {
+	a := NewMerkle[*testInt](2, hashTestInt)
+	b := NewMerkle[*testInt](16, hashTestInt)
+	for _, v := range rand.Perm(1000) {
+		a.ReplaceOrInsert(newTestInt(v))
+	}
+	for v := 999; v >= 0; v-- {
+		b.ReplaceOrInsert(newTestInt(v))
+	}
+	if a.RootHash() != b.RootHash() {
+		t.Fatal("trees holding the same items hash differently")
+	}
+	b.Delete(newTestInt(500))
+	if a.RootHash() == b.RootHash() {
+		t.Fatal("trees holding different items hash the same")
+	}
+	lo, hi := Bound[*testInt]{Item: newTestInt(0), Inclusive: true}, Bound[*testInt]{Item: newTestInt(500)}
+	if a.RangeHash(lo, hi) != b.RangeHash(lo, hi) {
+		t.Fatal("identical ranges hash differently")
+	}
+}
+
+func TestMerkleLazy(t *testing.T) {
+	calls := 0
+	tr := NewMerkle[*testInt](4, func(i *testInt) [32]byte {
+		calls++
+		return hashTestInt(i)
+	})
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	if calls != 0 {
+		t.Fatalf("%v items hashed before asking for a hash", calls)
+	}
+	tr.RootHash()
+	if calls != 1000 {
+		t.Fatalf("%v items hashed, want 1000", calls)
+	}
+	calls = 0
+	tr.RootHash()
+	if calls != 0 {
+		t.Fatalf("%v items hashed again", calls)
+	}
+	tr.Delete(newTestInt(500))
+	tr.RootHash()
+	// Rebalancing may invalidate a sibling of each node along the path.
+	height := 1
+	for n := tr.root; len(n.children) > 0; n = n.children[0] {
+		height++
+	}
+	if calls == 0 || calls > 2*height*tr.maxItems() {
+		t.Fatalf("%v items hashed after a single deletion", calls)
+	}
+}
+
+func BenchmarkRootHash(b *testing.B) {
+	tr := NewMerkle[*testInt](*btreeDegree, hashTestInt)
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(newTestInt(v))
+	}
+	tr.RootHash()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.ReplaceOrInsert(newTestInt(i % 10000))
+		tr.RootHash()
+	}
+}
@@ -0,0 +1,115 @@
+package btree
+
+// Op is the kind of a change made to a tree.
+type Op int
+
+const (
+	OpInsert  Op = iota // an item was added to the tree
+	OpReplace           // an item of the tree was replaced by an equal one
+	OpDelete            // an item was removed from the tree
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpInsert:
+		return "insert"
+	case OpReplace:
+		return "replace"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Change describes a change made to a tree.  Old is the zero value for
+// insertions, and New the zero value for deletions.
+type Change[T Item[T]] struct {
+	Op       Op
+	Old, New T
+}
+
+// Observer is notified of the changes made to a tree it observes, see
+// BTree.Observe.
+//
+// Observers are called synchronously, once the tree is in a consistent state
+// again, by the goroutine which modified the tree.  They may read the tree
+// but must not modify it.
+type Observer[T Item[T]] interface {
+	OnInsert(item T)
+	OnReplace(old, new T)
+	OnDelete(item T)
+}
+
+// BatchObserver is an Observer which is notified at once of all the changes
+// made by a bulk operation such as ReplaceOrInsertMany, DeleteMany or Clear.
+// Observers which don't implement it get one call per changed item instead.
+//
+// The changes slice must not be retained after OnBatch returns.
+type BatchObserver[T Item[T]] interface {
+	Observer[T]
+	OnBatch(changes []Change[T])
+}
+
+// observation wraps a registered Observer so that it can be told apart from
+// other registrations of the same Observer.
+type observation[T Item[T]] struct {
+	Observer[T]
+}
+
+// Observe registers o to be notified of the changes made to the tree, until
+// the returned cancel function is called.
+//
+// Copies of the tree are not observed.
+func (t *BTree[T]) Observe(o Observer[T]) (cancel func()) {
+	obs := &observation[T]{o}
+	t.observers = append(t.observers[:len(t.observers):len(t.observers)], obs)
+
+	return func() {
+		for i, o := range t.observers {
+			if o == obs {
+				// Build a new slice so that a notification in progress isn't
+				// disturbed by an observer cancelling itself.
+				observers := make([]*observation[T], 0, len(t.observers)-1)
+				observers = append(observers, t.observers[:i]...)
+				t.observers = append(observers, t.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// dispatch calls the Observer callback matching c.
+func (obs *observation[T]) dispatch(c Change[T]) {
+	switch c.Op {
+	case OpInsert:
+		obs.OnInsert(c.New)
+	case OpReplace:
+		obs.OnReplace(c.Old, c.New)
+	case OpDelete:
+		obs.OnDelete(c.Old)
+	}
+}
+
+// notify notifies the observers of the tree of a single change.
+func (t *BTree[T]) notify(c Change[T]) {
+	for _, obs := range t.observers {
+		obs.dispatch(c)
+	}
+}
+
+// notifyBatch notifies the observers of the tree of the changes made by a
+// bulk operation.
+func (t *BTree[T]) notifyBatch(changes []Change[T]) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, obs := range t.observers {
+		if b, ok := obs.Observer.(BatchObserver[T]); ok {
+			b.OnBatch(changes)
+			continue
+		}
+		for _, c := range changes {
+			obs.dispatch(c)
+		}
+	}
+}
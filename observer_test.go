@@ -0,0 +1,160 @@
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mirror is an Observer which replays the changes it is notified of on a
+// map.
+type mirror struct {
+	t     *testing.T
+	items map[int]*testInt
+	calls int
+}
+
+func (m *mirror) OnInsert(item *testInt) {
+	if _, ok := m.items[int(*item)]; ok {
+		m.t.Fatalf("insert of %v which is already there", *item)
+	}
+	m.items[int(*item)] = item
+	m.calls++
+}
+
+func (m *mirror) OnReplace(old, new *testInt) {
+	if m.items[int(*old)] != old {
+		m.t.Fatalf("replace of %v which isn't there", *old)
+	}
+	m.items[int(*new)] = new
+	m.calls++
+}
+
+func (m *mirror) OnDelete(item *testInt) {
+	if m.items[int(*item)] != item {
+		m.t.Fatalf("delete of %v which isn't there", *item)
+	}
+	delete(m.items, int(*item))
+	m.calls++
+}
+
+// batchMirror is a mirror which is notified of bulk changes at once.
+type batchMirror struct {
+	mirror
+	batches int
+}
+
+func (m *batchMirror) OnBatch(changes []Change[*testInt]) {
+	for _, c := range changes {
+		switch c.Op {
+		case OpInsert:
+			m.OnInsert(c.New)
+		case OpReplace:
+			m.OnReplace(c.Old, c.New)
+		case OpDelete:
+			m.OnDelete(c.Old)
+		}
+	}
+	m.batches++
+}
+
+func (m *mirror) check(tr *BTree[*testInt]) {
+	m.t.Helper()
+	var want, got []int
+	tr.Ascend(func(item *testInt) bool {
+		want = append(want, int(*item))
+		if m.items[int(*item)] != item {
+			m.t.Fatalf("item %v isn't mirrored", *item)
+		}
+		return true
+	})
+	for v := range m.items {
+		got = append(got, v)
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, want) {
+		m.t.Fatalf("mirror has %v, tree has %v", got, want)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	m := &mirror{t: t, items: map[int]*testInt{}}
+	bm := &batchMirror{mirror: mirror{t: t, items: map[int]*testInt{}}}
+	tr.Observe(m)
+	tr.Observe(bm)
+
+	for i := 0; i < 2000; i++ {
+		switch v := rand.Intn(200); rand.Intn(9) {
+		case 0, 1, 2:
+			tr.ReplaceOrInsert(newTestInt(v))
+		case 3:
+			tr.Delete(newTestInt(v))
+		case 4:
+			tr.DeleteMin()
+		case 5:
+			tr.DeleteMax()
+		case 6:
+			var batch []*testInt
+			for _, v := range rand.Perm(200)[:rand.Intn(50)] {
+				batch = append(batch, newTestInt(v))
+			}
+			tr.ReplaceOrInsertMany(batch)
+		case 7:
+			var keys []*testInt
+			for _, v := range rand.Perm(200)[:rand.Intn(50)] {
+				keys = append(keys, newTestInt(v))
+			}
+			tr.DeleteMany(keys)
+		case 8:
+			action := UpsertAction(rand.Intn(4))
+			tr.Upsert(newTestInt(v), func(*testInt, bool) (*testInt, UpsertAction) {
+				return newTestInt(v), action
+			})
+		}
+		if rand.Intn(500) == 0 {
+			tr.Clear(false)
+		}
+		m.check(tr)
+		bm.check(tr)
+	}
+	if bm.batches == 0 {
+		t.Fatal("batch observer was never notified of a batch")
+	}
+}
+
+func TestObserverCancel(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	m := &mirror{t: t, items: map[int]*testInt{}}
+	cancel := tr.Observe(m)
+	var other mirror
+	cancelOther := tr.Observe(&other)
+	cancelOther()
+
+	tr.ReplaceOrInsert(newTestInt(1))
+	cancel()
+	cancel()
+	tr.ReplaceOrInsert(newTestInt(2))
+	tr.Delete(newTestInt(1))
+	if m.calls != 1 || other.calls != 0 {
+		t.Fatalf("got %v and %v calls, want 1 and 0", m.calls, other.calls)
+	}
+}
+
+func BenchmarkInsertObserved(b *testing.B) {
+	insertP := rand.Perm(10000)
+	b.ResetTimer()
+	i := 0
+	for i < b.N {
+		tr := New[*testInt](*btreeDegree)
+		tr.Observe(&mirror{items: map[int]*testInt{}})
+		for _, item := range insertP {
+			tr.ReplaceOrInsert(newTestInt(item))
+			i++
+			if i >= b.N {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package btree
+
+// AscendPrefix calls the iterator, in ascending order, for every item of the
+// tree whose key starts with prefix, until iterator returns false.
+//
+// The items of the tree must be ordered by their key, compared byte-wise as
+// strings.Compare or bytes.Compare do.  pivot returns an item with the given
+// key, it is only used to seek the tree.
+func AscendPrefix[T Item[T], K ~string | ~[]byte](t *BTree[T], prefix K, pivot func(key K) T, iterator ItemIterator[T]) {
+	lo, hi := prefixRange(prefix, pivot)
+	t.Range(lo, hi, Ascending).Each(iterator)
+}
+
+// DescendPrefix calls the iterator, in descending order, for every item of
+// the tree whose key starts with prefix, until iterator returns false.
+//
+// See AscendPrefix for the requirements on the tree and pivot.
+func DescendPrefix[T Item[T], K ~string | ~[]byte](t *BTree[T], prefix K, pivot func(key K) T, iterator ItemIterator[T]) {
+	lo, hi := prefixRange(prefix, pivot)
+	t.Range(lo, hi, Descending).Each(iterator)
+}
+
+// prefixRange returns the bounds of the range of keys starting with prefix.
+func prefixRange[T Item[T], K ~string | ~[]byte](prefix K, pivot func(key K) T) (lo, hi Bound[T]) {
+	lo = Bound[T]{Item: pivot(prefix), Inclusive: true}
+	if next, ok := prefixSuccessor(prefix); ok {
+		hi = Bound[T]{Item: pivot(next)}
+	} else {
+		hi = Unbounded[T]()
+	}
+	return lo, hi
+}
+
+// prefixSuccessor returns the lowest key greater than all the keys starting
+// with prefix.  There is none when prefix is empty or only made of 0xff
+// bytes, as every key greater than prefix then starts with it.
+func prefixSuccessor[K ~string | ~[]byte](prefix K) (next K, ok bool) {
+	b := make([]byte, len(prefix))
+	copy(b, prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return K(b[:i+1]), true
+		}
+	}
+	return next, false
+}
@@ -0,0 +1,79 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type testString string
+
+func (s testString) Less(s2 testString) bool { return s < s2 }
+func (s testString) DeepCopy() testString    { return s }
+
+func TestPrefixSuccessor(t *testing.T) {
+	for _, test := range []struct {
+		prefix, next string
+		ok           bool
+	}{
+		{"", "", false},
+		{"a", "b", true},
+		{"ab", "ac", true},
+		{"a\xff", "b", true},
+		{"a\xff\xff", "b", true},
+		{"\xff\xff", "", false},
+	} {
+		next, ok := prefixSuccessor(test.prefix)
+		if next != test.next || ok != test.ok {
+			t.Errorf("successor(%q): got (%q, %v), want (%q, %v)", test.prefix, next, ok, test.next, test.ok)
+		}
+		nextb, ok := prefixSuccessor([]byte(test.prefix))
+		if string(nextb) != test.next || ok != test.ok {
+			t.Errorf("successor([]byte(%q)): got (%q, %v), want (%q, %v)", test.prefix, nextb, ok, test.next, test.ok)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	keys := []string{
+		"", "a", "a/b", "a/b/c", "a/bc", "a/c", "a\xff", "a\xff\x00", "a\xff\xff", "b",
+		"\xff", "\xff\x00", "\xff\xff", "\xff\xff\xff",
+	}
+	tr := New[testString](2)
+	for _, key := range keys {
+		tr.ReplaceOrInsert(testString(key))
+	}
+	for _, prefix := range append(keys, "a/", "a/b/", "c", "\xff\xff\xff\xff") {
+		var want []testString
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				want = append(want, testString(key))
+			}
+		}
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+		var got []testString
+		AscendPrefix(tr, prefix, func(key string) testString { return testString(key) }, func(item testString) bool {
+			got = append(got, item)
+			return true
+		})
+		if len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Fatalf("ascend prefix %q:\n got: %q\nwant: %q", prefix, got, want)
+		}
+
+		got = got[:0]
+		DescendPrefix(tr, []byte(prefix), func(key []byte) testString { return testString(key) }, func(item testString) bool {
+			got = append(got, item)
+			return true
+		})
+		for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+			want[i], want[j] = want[j], want[i]
+		}
+		if len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Fatalf("descend prefix %q:\n got: %q\nwant: %q", prefix, got, want)
+		}
+	}
+}
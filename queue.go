@@ -0,0 +1,108 @@
+package btree
+
+// queueEntry is an item of a PriorityQueue.  Equal items are told apart, and
+// ordered, by the sequence number of their push.
+type queueEntry[T Item[T]] struct {
+	item T
+	seq  uint64
+}
+
+func (e queueEntry[T]) Less(e2 queueEntry[T]) bool {
+	switch {
+	case e.item.Less(e2.item):
+		return true
+	case e2.item.Less(e.item):
+		return false
+	}
+	return e.seq < e2.seq
+}
+
+func (e queueEntry[T]) DeepCopy() queueEntry[T] {
+	return queueEntry[T]{item: e.item.DeepCopy(), seq: e.seq}
+}
+
+// PriorityQueue is a double-ended priority queue: both its lowest and its
+// highest items can be peeked at and popped in O(log n).
+//
+// Unlike a BTree, a PriorityQueue can hold several equal items.  PopMin
+// returns equal items in the order they were pushed, and PopMax in the
+// reverse order.
+type PriorityQueue[T Item[T]] struct {
+	tree *BTree[queueEntry[T]]
+	seq  uint64
+}
+
+// NewPriorityQueue creates a new priority queue backed by a B-Tree of the
+// given degree.
+func NewPriorityQueue[T Item[T]](degree int) *PriorityQueue[T] {
+	return &PriorityQueue[T]{tree: New[queueEntry[T]](degree)}
+}
+
+// Push adds an item to the queue.
+func (q *PriorityQueue[T]) Push(item T) {
+	q.seq++
+	q.tree.ReplaceOrInsert(queueEntry[T]{item: item, seq: q.seq})
+}
+
+// PeekMin returns the lowest item of the queue, or (zeroValue, false) if the
+// queue is empty.
+func (q *PriorityQueue[T]) PeekMin() (_ T, _ bool) {
+	e, ok := q.tree.Min()
+	return e.item, ok
+}
+
+// PeekMax returns the highest item of the queue, or (zeroValue, false) if the
+// queue is empty.
+func (q *PriorityQueue[T]) PeekMax() (_ T, _ bool) {
+	e, ok := q.tree.Max()
+	return e.item, ok
+}
+
+// PopMin removes the lowest item of the queue and returns it, or returns
+// (zeroValue, false) if the queue is empty.
+func (q *PriorityQueue[T]) PopMin() (_ T, _ bool) {
+	e, ok := q.tree.DeleteMin()
+	return e.item, ok
+}
+
+// PopMax removes the highest item of the queue and returns it, or returns
+// (zeroValue, false) if the queue is empty.
+func (q *PriorityQueue[T]) PopMax() (_ T, _ bool) {
+	e, ok := q.tree.DeleteMax()
+	return e.item, ok
+}
+
+// Update replaces the first pushed item equal to old by new, which takes the
+// place of a newly pushed item.  It returns false, and leaves the queue
+// untouched, if there is no item equal to old.
+func (q *PriorityQueue[T]) Update(old, new T) bool {
+	// Sequence numbers start at 1, so the pivot comes before every item equal
+	// to old.
+	e, ok := q.tree.Ceil(queueEntry[T]{item: old})
+	if !ok || old.Less(e.item) {
+		return false
+	}
+	q.tree.Delete(e)
+	q.Push(new)
+
+	return true
+}
+
+// DrainWhile pops the lowest items of the queue as long as fn, which is
+// called with each of them, returns true.  The item for which fn returns
+// false is left in the queue.  It returns the number of popped items.
+func (q *PriorityQueue[T]) DrainWhile(fn func(item T) bool) (n int) {
+	for {
+		e, ok := q.tree.Min()
+		if !ok || !fn(e.item) {
+			return n
+		}
+		q.tree.DeleteMin()
+		n++
+	}
+}
+
+// Len returns the number of items in the queue.
+func (q *PriorityQueue[T]) Len() int {
+	return q.tree.Len()
+}
@@ -0,0 +1,9 @@
+//go:build goexperiment.arenas
+
+package btree
+
+import "arena"
+
+func (e queueEntry[T]) DeepCopyWithArena(a *arena.Arena) queueEntry[T] {
+	return queueEntry[T]{item: e.item.DeepCopyWithArena(a), seq: e.seq}
+}
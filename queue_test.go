@@ -0,0 +1,135 @@
+//go:build !goexperiment.arenas
+
+package btree
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// testEvent has a priority and an identity, so that the order in which equal
+// items are popped can be checked.
+type testEvent struct {
+	prio, id int
+}
+
+func (e *testEvent) Less(e2 *testEvent) bool { return e.prio < e2.prio }
+
+func (e *testEvent) DeepCopy() *testEvent {
+	e2 := *e
+	return &e2
+}
+
+func TestPriorityQueue(t *testing.T) {
+	q := NewPriorityQueue[*testEvent](*btreeDegree)
+	var want []*testEvent // sorted by priority, equal items in push order
+	insert := func(e *testEvent) {
+		i := sort.Search(len(want), func(i int) bool { return e.prio < want[i].prio })
+		want = append(want[:i], append([]*testEvent{e}, want[i:]...)...)
+	}
+
+	for i := 0; i < 5000; i++ {
+		switch rand.Intn(6) {
+		case 0, 1, 2:
+			e := &testEvent{prio: rand.Intn(50), id: i}
+			q.Push(e)
+			insert(e)
+		case 3:
+			e, ok := q.PopMin()
+			if ok != (len(want) > 0) || ok && e != want[0] {
+				t.Fatalf("pop min: got %v, %v", e, ok)
+			}
+			if ok {
+				want = want[1:]
+			}
+		case 4:
+			e, ok := q.PopMax()
+			if ok != (len(want) > 0) || ok && e != want[len(want)-1] {
+				t.Fatalf("pop max: got %v, %v", e, ok)
+			}
+			if ok {
+				want = want[:len(want)-1]
+			}
+		case 5:
+			old := &testEvent{prio: rand.Intn(50)}
+			updated := &testEvent{prio: rand.Intn(50), id: i}
+			j := sort.Search(len(want), func(j int) bool { return old.prio <= want[j].prio })
+			found := j < len(want) && want[j].prio == old.prio
+			if q.Update(old, updated) != found {
+				t.Fatalf("update of %v: want %v", old.prio, found)
+			}
+			if found {
+				want = append(want[:j], want[j+1:]...)
+				insert(updated)
+			}
+		}
+		if q.Len() != len(want) {
+			t.Fatalf("len %v, want %v", q.Len(), len(want))
+		}
+		if len(want) > 0 {
+			if e, _ := q.PeekMin(); e != want[0] {
+				t.Fatalf("peek min %v, want %v", e, want[0])
+			}
+			if e, _ := q.PeekMax(); e != want[len(want)-1] {
+				t.Fatalf("peek max %v, want %v", e, want[len(want)-1])
+			}
+		}
+	}
+
+	n := q.DrainWhile(func(e *testEvent) bool { return e.prio < 25 })
+	for _, e := range want[:n] {
+		if e.prio >= 25 {
+			t.Fatalf("drained %v", e)
+		}
+	}
+	if e, ok := q.PeekMin(); ok && e.prio < 25 {
+		t.Fatalf("%v left after draining", e)
+	}
+}
+
+// testIntHeap is a container/heap of testInts.
+type testIntHeap []*testInt
+
+func (h testIntHeap) Len() int            { return len(h) }
+func (h testIntHeap) Less(i, j int) bool  { return *h[i] < *h[j] }
+func (h testIntHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *testIntHeap) Push(x interface{}) { *h = append(*h, x.(*testInt)) }
+func (h *testIntHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func BenchmarkPriorityQueue(b *testing.B) {
+	insertP := rand.Perm(10000)
+	items := make([]*testInt, len(insertP))
+	for i, v := range insertP {
+		items[i] = newTestInt(v)
+	}
+
+	b.Run("PriorityQueue", func(b *testing.B) {
+		q := NewPriorityQueue[*testInt](*btreeDegree)
+		for _, item := range items {
+			q.Push(item)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.Push(items[i%len(items)])
+			q.PopMin()
+		}
+	})
+	b.Run("container/heap", func(b *testing.B) {
+		h := &testIntHeap{}
+		for _, item := range items {
+			heap.Push(h, item)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			heap.Push(h, items[i%len(items)])
+			heap.Pop(h)
+		}
+	})
+}
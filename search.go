@@ -0,0 +1,124 @@
+package btree
+
+// SearchStrategy tells how items are searched for within a node.
+type SearchStrategy int
+
+const (
+	// SearchBinary uses a binary search, which is the default.
+	SearchBinary SearchStrategy = iota
+	// SearchLinear scans the items of a node in order, which is faster than a
+	// binary search for small nodes and cheap comparisons.
+	SearchLinear
+	// SearchAdaptive scans nodes of up to adaptiveLinearMax items and uses a
+	// binary search for larger ones.
+	SearchAdaptive
+)
+
+// adaptiveLinearMax is the largest node scanned by SearchAdaptive.
+const adaptiveLinearMax = 16
+
+// SetSearchStrategy sets how the tree searches for items within its nodes.
+func (t *BTree[T]) SetSearchStrategy(strategy SearchStrategy) {
+	t.strategy = strategy
+}
+
+// Ordered is a constraint that permits any type supporting the < operator.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
+// orderedItem is an Ordered Item.
+type orderedItem[T any] interface {
+	Ordered
+	Item[T]
+}
+
+// orderedSearch holds the search functions of a tree whose items are
+// Ordered, which compare items with < instead of calling Less.
+type orderedSearch[T Item[T]] struct {
+	binary, linear func(s items[T], item T) (int, bool)
+}
+
+// NewOrdered creates a new B-Tree with the given degree, for items which are
+// Ordered values.  Such a tree compares items with the < operator rather than
+// through Less, which must order items the same way.
+//
+// Floating point items must not be NaN.
+func NewOrdered[T orderedItem[T]](degree int) *BTree[T] {
+	t := New[T](degree)
+	t.ordered = &orderedSearch[T]{
+		binary: findOrdered[T],
+		linear: findOrderedLinear[T],
+	}
+
+	return t
+}
+
+// search returns the index where the given item should be inserted into s,
+// and whether an equal item is already there, according to the search
+// strategy of the tree.
+func (t *BTree[T]) search(s items[T], item T) (index int, found bool) {
+	linear := t.strategy == SearchLinear || t.strategy == SearchAdaptive && len(s) <= adaptiveLinearMax
+	switch {
+	case t.ordered != nil && linear:
+		return t.ordered.linear(s, item)
+	case t.ordered != nil:
+		return t.ordered.binary(s, item)
+	case t.cmp && linear:
+		return s.findCompareLinear(any(item).(Comparable[T]))
+	case t.cmp:
+		return s.findCompare(any(item).(Comparable[T]))
+	case linear:
+		return s.findLinear(item)
+	}
+	return s.find(item)
+}
+
+// findLinear is find scanning s in order.
+func (s items[T]) findLinear(item T) (index int, found bool) {
+	for i, v := range s {
+		if !v.Less(item) {
+			return i, !item.Less(v)
+		}
+	}
+	return len(s), false
+}
+
+// findCompareLinear is findCompare scanning s in order.
+func (s items[T]) findCompareLinear(item Comparable[T]) (index int, found bool) {
+	for i, v := range s {
+		if c := item.Compare(v); c <= 0 {
+			return i, c == 0
+		}
+	}
+	return len(s), false
+}
+
+// findOrdered is find for Ordered items.
+func findOrdered[T orderedItem[T]](s items[T], item T) (index int, found bool) {
+	lo, hi := 0, len(s)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if s[m] < item {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return lo, lo < len(s) && s[lo] == item
+}
+
+// findOrderedLinear is findOrdered counting the items lower than item rather
+// than stopping at the first greater one, which compiles to a loop without
+// unpredictable branches.
+func findOrderedLinear[T orderedItem[T]](s items[T], item T) (index int, found bool) {
+	for _, v := range s {
+		if v < item {
+			index++
+		}
+	}
+	return index, index < len(s) && s[index] == item
+}
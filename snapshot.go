@@ -0,0 +1,212 @@
+package btree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Snapshot files, as written by WriteSnapshot, are made of pages of
+// SnapshotPageSize bytes.  Every node of the tree is stored in its own run of
+// consecutive pages, children before their parent, and the file ends with a
+// footer page locating the root.
+//
+// A node is laid out as:
+//
+//	flags    uint32, snapshotLeaf if the node has no children
+//	count    uint32, number of items
+//	children count+1 uint64 page numbers, internal nodes only
+//	offsets  count+1 uint32, offsets[i] and offsets[i+1] delimit item i
+//	data     the encoded items
+//
+// All integers are little endian and offsets are relative to the start of the
+// node.
+const SnapshotPageSize = 4096
+
+const (
+	snapshotMagic   = "btreesnp"
+	snapshotVersion = 1
+
+	snapshotLeaf = 1
+
+	// snapshotNoRoot is the root page number of an empty tree.
+	snapshotNoRoot = ^uint64(0)
+
+	snapshotNodeHeaderSize = 8
+	snapshotFooterSize     = 40
+)
+
+// snapshotFooter is the content of the last page of a snapshot.
+type snapshotFooter struct {
+	root   uint64
+	length uint64
+	degree uint32
+}
+
+func (f snapshotFooter) appendTo(b []byte) []byte {
+	b = append(b, snapshotMagic...)
+	b = binary.LittleEndian.AppendUint32(b, snapshotVersion)
+	b = binary.LittleEndian.AppendUint32(b, SnapshotPageSize)
+	b = binary.LittleEndian.AppendUint64(b, f.root)
+	b = binary.LittleEndian.AppendUint64(b, f.length)
+	b = binary.LittleEndian.AppendUint32(b, f.degree)
+	return append(b, make([]byte, 4)...)
+}
+
+func parseSnapshotFooter(page []byte) (f snapshotFooter, err error) {
+	if len(page) < snapshotFooterSize || string(page[:8]) != snapshotMagic {
+		return f, errors.New("btree: not a snapshot")
+	}
+	if v := binary.LittleEndian.Uint32(page[8:]); v != snapshotVersion {
+		return f, fmt.Errorf("btree: unsupported snapshot version %d", v)
+	}
+	if s := binary.LittleEndian.Uint32(page[12:]); s != SnapshotPageSize {
+		return f, fmt.Errorf("btree: unsupported snapshot page size %d", s)
+	}
+	f.root = binary.LittleEndian.Uint64(page[16:])
+	f.length = binary.LittleEndian.Uint64(page[24:])
+	f.degree = binary.LittleEndian.Uint32(page[32:])
+	return f, nil
+}
+
+// snapshotWriter writes the nodes of a tree, post-order, to a snapshot.
+type snapshotWriter[T Item[T]] struct {
+	w      *bufio.Writer
+	encode func(T) ([]byte, error)
+	page   uint64
+	buf    []byte
+}
+
+// WriteSnapshot writes t to w as an immutable snapshot which can then be
+// memory-mapped and queried without being loaded, see OpenMapped.
+//
+// encode returns the on-disk representation of an item, the comparison
+// function given to OpenMapped must order the encoded items as Less orders
+// the items.
+func WriteSnapshot[T Item[T]](w io.Writer, t *BTree[T], encode func(T) ([]byte, error)) error {
+	sw := &snapshotWriter[T]{
+		w:      bufio.NewWriterSize(w, SnapshotPageSize),
+		encode: encode,
+	}
+
+	footer := snapshotFooter{root: snapshotNoRoot, length: uint64(t.Len()), degree: uint32(t.degree)}
+	if t.root != nil && len(t.root.items) > 0 {
+		root, err := sw.writeNode(t.root)
+		if err != nil {
+			return err
+		}
+		footer.root = root
+	}
+	if err := sw.writePages(footer.appendTo(sw.buf[:0])); err != nil {
+		return err
+	}
+
+	return sw.w.Flush()
+}
+
+// writeNode writes the subtree rooted at n and returns the page number of n.
+func (sw *snapshotWriter[T]) writeNode(n *node[T]) (uint64, error) {
+	children := make([]uint64, len(n.children))
+	for i, child := range n.children {
+		page, err := sw.writeNode(child)
+		if err != nil {
+			return 0, err
+		}
+		children[i] = page
+	}
+
+	var flags uint32
+	if len(n.children) == 0 {
+		flags = snapshotLeaf
+	}
+	b := binary.LittleEndian.AppendUint32(sw.buf[:0], flags)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(n.items)))
+	for _, page := range children {
+		b = binary.LittleEndian.AppendUint64(b, page)
+	}
+	offsets := len(b)
+	b = append(b, make([]byte, 4*(len(n.items)+1))...)
+	for i, item := range n.items {
+		binary.LittleEndian.PutUint32(b[offsets+4*i:], uint32(len(b)))
+		data, err := sw.encode(item)
+		if err != nil {
+			return 0, err
+		}
+		b = append(b, data...)
+	}
+	binary.LittleEndian.PutUint32(b[offsets+4*len(n.items):], uint32(len(b)))
+	sw.buf = b
+
+	page := sw.page
+	return page, sw.writePages(b)
+}
+
+// writePages writes b padded to a whole number of pages.
+func (sw *snapshotWriter[T]) writePages(b []byte) error {
+	pages := (len(b) + SnapshotPageSize - 1) / SnapshotPageSize
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+	if _, err := sw.w.Write(make([]byte, pages*SnapshotPageSize-len(b))); err != nil {
+		return err
+	}
+	sw.page += uint64(pages)
+	return nil
+}
+
+// snapshotNode reads a node of a snapshot.
+type snapshotNode []byte
+
+func (n snapshotNode) leaf() bool {
+	return binary.LittleEndian.Uint32(n)&snapshotLeaf != 0
+}
+
+func (n snapshotNode) count() int {
+	return int(binary.LittleEndian.Uint32(n[4:]))
+}
+
+func (n snapshotNode) child(i int) uint64 {
+	return binary.LittleEndian.Uint64(n[snapshotNodeHeaderSize+8*i:])
+}
+
+// check returns an error unless n holds a well-formed node at the given page,
+// whose children precede it as WriteSnapshot writes them.
+func (n snapshotNode) check(page uint64) error {
+	if len(n) < snapshotNodeHeaderSize {
+		return errors.New("btree: corrupted snapshot: truncated node")
+	}
+	count := uint64(n.count())
+	offsets := uint64(snapshotNodeHeaderSize)
+	if !n.leaf() {
+		offsets += 8 * (count + 1)
+	}
+	prev := offsets + 4*(count+1)
+	if prev > uint64(len(n)) {
+		return errors.New("btree: corrupted snapshot: truncated node")
+	}
+	for i := 0; !n.leaf() && i <= int(count); i++ {
+		if n.child(i) >= page {
+			return fmt.Errorf("btree: corrupted snapshot: bad child page %d", n.child(i))
+		}
+	}
+	for i := uint64(0); i <= count; i++ {
+		offset := uint64(binary.LittleEndian.Uint32(n[offsets+4*i:]))
+		if offset < prev || offset > uint64(len(n)) {
+			return fmt.Errorf("btree: corrupted snapshot: bad item offset %d", offset)
+		}
+		prev = offset
+	}
+	return nil
+}
+
+func (n snapshotNode) item(i int) []byte {
+	offsets := snapshotNodeHeaderSize
+	if !n.leaf() {
+		offsets += 8 * (n.count() + 1)
+	}
+	start := binary.LittleEndian.Uint32(n[offsets+4*i:])
+	end := binary.LittleEndian.Uint32(n[offsets+4*i+4:])
+	return n[start:end:end]
+}
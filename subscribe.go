@@ -0,0 +1,162 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSlowConsumer is the error of a Subscription using BackpressureError
+// whose buffer overflowed.
+var ErrSlowConsumer = errors.New("btree: subscription buffer is full")
+
+// Event is a change made to a tree, as delivered to a Subscription.
+type Event[T Item[T]] struct {
+	// Seq numbers the changes made to the tree since the subscription, the
+	// first one being 1.
+	Seq uint64
+	Change[T]
+}
+
+// Backpressure tells what a Subscription does with an event when its buffer
+// is full.
+type Backpressure int
+
+const (
+	// BackpressureBlock blocks the goroutine modifying the tree until the
+	// consumer makes room for the event, or until the subscription is done.
+	BackpressureBlock Backpressure = iota
+	// BackpressureDrop drops the event.  Consumers can detect dropped events
+	// through the gaps they leave between sequence numbers.
+	BackpressureDrop
+	// BackpressureError drops the event and ends the subscription, whose Err
+	// then returns ErrSlowConsumer.
+	BackpressureError
+)
+
+// Subscription is a stream of the changes made to a tree, see
+// BTree.Subscribe.
+type Subscription[T Item[T]] struct {
+	// C delivers the events, it is closed when the subscription ends.
+	C <-chan Event[T]
+
+	c      chan Event[T]
+	ctx    context.Context
+	policy Backpressure
+	cancel func()
+	done   chan struct{}
+
+	// send is held while sending to c, and while closing it.  The only
+	// blocking send, with BackpressureBlock, gives up once ctx is done, which
+	// is when the subscription is closed from another goroutine.
+	send sync.Mutex
+	seq  uint64 // guarded by send
+
+	// mu guards the state read by Err, and is never held while sending.
+	mu     sync.Mutex
+	closed bool // only set with send held too
+	err    error
+}
+
+// Subscribe returns a Subscription delivering the changes made to the tree
+// through a channel buffering up to bufSize events, until ctx is done.  policy
+// tells what happens when a change is made while the buffer is full.
+//
+// Unlike the rest of the tree, the channel of the subscription may be read
+// from any goroutine.  The items of the events are shared with the tree and
+// must not be modified.
+func (t *BTree[T]) Subscribe(ctx context.Context, bufSize int, policy Backpressure) *Subscription[T] {
+	c := make(chan Event[T], bufSize)
+	s := &Subscription[T]{
+		C:      c,
+		c:      c,
+		ctx:    ctx,
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	s.cancel = t.Observe(subscriber[T]{s})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.send.Lock()
+			s.close(ctx.Err())
+			s.send.Unlock()
+		case <-s.done:
+		}
+	}()
+
+	return s
+}
+
+// Err returns nil while the subscription is active, and the reason why it
+// ended afterwards: either the error of its context or ErrSlowConsumer.
+func (s *Subscription[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// close ends the subscription, s.send must be held.
+func (s *Subscription[T]) close(err error) {
+	if s.closed {
+		return
+	}
+	s.mu.Lock()
+	s.closed, s.err = true, err
+	s.mu.Unlock()
+	close(s.c)
+	close(s.done)
+}
+
+// publish delivers a change according to the backpressure policy.  It is
+// called by the goroutine modifying the tree, which is the only one allowed to
+// unregister the subscription from the tree.
+func (s *Subscription[T]) publish(c Change[T]) {
+	s.send.Lock()
+	defer s.send.Unlock()
+	if s.closed {
+		s.cancel()
+		return
+	}
+
+	s.seq++
+	e := Event[T]{Seq: s.seq, Change: c}
+	switch s.policy {
+	case BackpressureBlock:
+		select {
+		case s.c <- e:
+		case <-s.ctx.Done():
+		}
+	case BackpressureDrop:
+		select {
+		case s.c <- e:
+		default:
+		}
+	case BackpressureError:
+		select {
+		case s.c <- e:
+		default:
+			s.close(ErrSlowConsumer)
+			s.cancel()
+		}
+	}
+}
+
+// subscriber is the Observer through which a Subscription is notified, it
+// keeps the callbacks out of the Subscription's method set.
+type subscriber[T Item[T]] struct {
+	s *Subscription[T]
+}
+
+func (o subscriber[T]) OnInsert(item T) {
+	o.s.publish(Change[T]{Op: OpInsert, New: item})
+}
+
+func (o subscriber[T]) OnReplace(old, new T) {
+	o.s.publish(Change[T]{Op: OpReplace, Old: old, New: new})
+}
+
+func (o subscriber[T]) OnDelete(item T) {
+	o.s.publish(Change[T]{Op: OpDelete, Old: item})
+}
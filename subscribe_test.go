@@ -0,0 +1,151 @@
+package btree
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscribeBlock(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := tr.Subscribe(ctx, 1, BackpressureBlock)
+
+	// Replicate the tree from another goroutine.
+	replica := make(chan *BTree[*testInt])
+	go func() {
+		r := New[*testInt](*btreeDegree)
+		var seq uint64
+		for e := range sub.C {
+			if e.Seq != seq+1 {
+				t.Errorf("got event %v after %v", e.Seq, seq)
+			}
+			seq = e.Seq
+			switch e.Op {
+			case OpInsert, OpReplace:
+				r.ReplaceOrInsert(e.New)
+			case OpDelete:
+				r.Delete(e.Old)
+			}
+		}
+		replica <- r
+	}()
+
+	for i := 0; i < 1000; i++ {
+		switch v := rand.Intn(100); rand.Intn(3) {
+		case 0, 1:
+			tr.ReplaceOrInsert(newTestInt(v))
+		case 2:
+			tr.Delete(newTestInt(v))
+		}
+	}
+	tr.DeleteMany([]*testInt{newTestInt(1), newTestInt(2)})
+	cancel()
+
+	r := <-replica
+	if got, want := intAll(r), intAll(tr); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replica has %v, want %v", got, want)
+	}
+	if err := sub.Err(); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSubscribeErrWhileBlocked(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := tr.Subscribe(ctx, 1, BackpressureBlock)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			tr.ReplaceOrInsert(newTestInt(i))
+		}
+	}()
+	// Wait for the producer to block on the second event.
+	for len(sub.C) < cap(sub.C) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	errc := make(chan error, 1)
+	go func() { errc <- sub.Err() }()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Err blocks while the producer is blocked")
+	}
+
+	for i := 0; i < 3; i++ {
+		if e := <-sub.C; int(*e.New) != i {
+			t.Fatalf("got event %+v, want insert of %v", e, i)
+		}
+	}
+	<-done
+	cancel()
+	for range sub.C {
+	}
+	if err := sub.Err(); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSubscribeDrop(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := tr.Subscribe(ctx, 10, BackpressureDrop)
+
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(newTestInt(i))
+	}
+	for i := 1; i <= 10; i++ {
+		if e := <-sub.C; e.Seq != uint64(i) || e.Op != OpInsert || int(*e.New) != i-1 {
+			t.Fatalf("got event %+v, want insert #%v", e, i)
+		}
+	}
+	tr.Delete(newTestInt(0))
+	if e := <-sub.C; e.Seq != 101 || e.Op != OpDelete {
+		t.Fatalf("got event %+v, want delete #101", e)
+	}
+	if err := sub.Err(); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestSubscribeError(t *testing.T) {
+	tr := New[*testInt](*btreeDegree)
+	sub := tr.Subscribe(context.Background(), 10, BackpressureError)
+
+	for i := 0; i < 11; i++ {
+		tr.ReplaceOrInsert(newTestInt(i))
+	}
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n != 10 {
+		t.Fatalf("got %v events, want 10", n)
+	}
+	if err := sub.Err(); err != ErrSlowConsumer {
+		t.Fatalf("got error %v, want %v", err, ErrSlowConsumer)
+	}
+	if len(tr.observers) != 0 {
+		t.Fatalf("subscription is still observing the tree")
+	}
+}
+
+// intAll returns the items of tr as ints.
+func intAll(tr *BTree[*testInt]) (out []int) {
+	tr.Ascend(func(item *testInt) bool {
+		out = append(out, int(*item))
+		return true
+	})
+	return out
+}
@@ -0,0 +1,200 @@
+package btree
+
+import "time"
+
+// ttlEntry is an item of the primary tree of a TTLTree, along with its
+// deadline.  A zero deadline never expires.
+type ttlEntry[T Item[T]] struct {
+	item     T
+	deadline time.Time
+}
+
+func (e ttlEntry[T]) Less(e2 ttlEntry[T]) bool {
+	return e.item.Less(e2.item)
+}
+
+func (e ttlEntry[T]) DeepCopy() ttlEntry[T] {
+	return ttlEntry[T]{item: e.item.DeepCopy(), deadline: e.deadline}
+}
+
+func (e ttlEntry[T]) expired(now time.Time) bool {
+	return !e.deadline.IsZero() && !now.Before(e.deadline)
+}
+
+// ttlDeadline is an item of the deadline index of a TTLTree, ordered by
+// deadline then by item.  A pivot has no item and comes after the items
+// sharing its deadline.
+type ttlDeadline[T Item[T]] struct {
+	deadline time.Time
+	item     T
+	pivot    bool
+}
+
+func (d ttlDeadline[T]) Less(d2 ttlDeadline[T]) bool {
+	switch {
+	case !d.deadline.Equal(d2.deadline):
+		return d.deadline.Before(d2.deadline)
+	case d.pivot || d2.pivot:
+		return !d.pivot && d2.pivot
+	}
+	return d.item.Less(d2.item)
+}
+
+func (d ttlDeadline[T]) DeepCopy() ttlDeadline[T] {
+	return ttlDeadline[T]{deadline: d.deadline, item: d.item.DeepCopy(), pivot: d.pivot}
+}
+
+// TTLTree is an ordered cache whose items may expire.
+//
+// Next to the tree of items, it keeps an index of the deadlines of the items
+// which expire.  Expired items are never returned, they are either removed
+// lazily when they are looked up or in bulk by Sweep.
+type TTLTree[T Item[T]] struct {
+	items     *BTree[ttlEntry[T]]
+	deadlines *BTree[ttlDeadline[T]]
+	now       func() time.Time
+}
+
+// NewTTL creates a new TTLTree with the given degree.
+func NewTTL[T Item[T]](degree int) *TTLTree[T] {
+	return NewTTLWithClock[T](degree, time.Now)
+}
+
+// NewTTLWithClock creates a new TTLTree with the given degree which gets the
+// current time from now.
+func NewTTLWithClock[T Item[T]](degree int, now func() time.Time) *TTLTree[T] {
+	return &TTLTree[T]{
+		items:     New[ttlEntry[T]](degree),
+		deadlines: New[ttlDeadline[T]](degree),
+		now:       now,
+	}
+}
+
+// Set adds the given item to the tree, where it doesn't expire.  If an item
+// in the tree already equals the given one, it is replaced and returned
+// unless it had expired.  Otherwise, (zeroValue, false) is returned.
+func (t *TTLTree[T]) Set(item T) (T, bool) {
+	return t.set(ttlEntry[T]{item: item})
+}
+
+// SetWithTTL adds the given item to the tree, where it expires after ttl.
+// The return values are the same as for Set.
+func (t *TTLTree[T]) SetWithTTL(item T, ttl time.Duration) (T, bool) {
+	return t.set(ttlEntry[T]{item: item, deadline: t.now().Add(ttl)})
+}
+
+func (t *TTLTree[T]) set(e ttlEntry[T]) (_ T, _ bool) {
+	old, ok := t.items.ReplaceOrInsert(e)
+	if ok && !old.deadline.IsZero() {
+		t.deadlines.Delete(ttlDeadline[T]{deadline: old.deadline, item: old.item})
+	}
+	if !e.deadline.IsZero() {
+		t.deadlines.ReplaceOrInsert(ttlDeadline[T]{deadline: e.deadline, item: e.item})
+	}
+	if !ok || old.expired(t.now()) {
+		return
+	}
+	return old.item, true
+}
+
+// lookup returns the entry equal to key, removing it if it has expired.
+func (t *TTLTree[T]) lookup(key T) (e ttlEntry[T], _ bool) {
+	e, ok := t.items.Get(ttlEntry[T]{item: key})
+	if !ok {
+		return
+	}
+	if e.expired(t.now()) {
+		t.remove(e)
+		return e, false
+	}
+	return e, true
+}
+
+// remove removes an entry from both trees.
+func (t *TTLTree[T]) remove(e ttlEntry[T]) {
+	t.items.Delete(e)
+	if !e.deadline.IsZero() {
+		t.deadlines.Delete(ttlDeadline[T]{deadline: e.deadline, item: e.item})
+	}
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item, or if it has expired, in
+// which case it is removed from the tree.
+func (t *TTLTree[T]) Get(key T) (_ T, _ bool) {
+	e, ok := t.lookup(key)
+	if !ok {
+		return
+	}
+	return e.item, true
+}
+
+// Has returns true if the given key is in the tree and hasn't expired.
+func (t *TTLTree[T]) Has(key T) bool {
+	_, ok := t.lookup(key)
+	return ok
+}
+
+// Deadline returns the time at which the key item expires, which is the zero
+// time if it doesn't.  It returns false if the item isn't in the tree or has
+// expired.
+func (t *TTLTree[T]) Deadline(key T) (_ time.Time, _ bool) {
+	e, ok := t.lookup(key)
+	if !ok {
+		return
+	}
+	return e.deadline, true
+}
+
+// Delete removes an item equal to the passed in item from the tree, returning
+// it.  If no such item exists, or if it has expired, returns
+// (zeroValue, false).
+func (t *TTLTree[T]) Delete(key T) (_ T, _ bool) {
+	e, ok := t.lookup(key)
+	if !ok {
+		return
+	}
+	t.remove(e)
+	return e.item, true
+}
+
+// Ascend calls the iterator for every item of the tree which hasn't expired,
+// in ascending order, until iterator returns false.
+func (t *TTLTree[T]) Ascend(iterator ItemIterator[T]) {
+	now := t.now()
+	t.items.Ascend(func(e ttlEntry[T]) bool {
+		return e.expired(now) || iterator(e.item)
+	})
+}
+
+// Len returns the number of items currently in the tree, including the
+// expired items which haven't been removed yet.
+func (t *TTLTree[T]) Len() int {
+	return t.items.Len()
+}
+
+// Sweep removes the items which have expired at now, and returns them in
+// ascending order.
+func (t *TTLTree[T]) Sweep(now time.Time) []T {
+	var expired []ttlDeadline[T]
+	t.deadlines.AscendLessThan(ttlDeadline[T]{deadline: now, pivot: true}, func(d ttlDeadline[T]) bool {
+		expired = append(expired, d)
+		return true
+	})
+	if len(expired) == 0 {
+		return nil
+	}
+	t.deadlines.DeleteMany(expired)
+
+	keys := make([]ttlEntry[T], len(expired))
+	for i, d := range expired {
+		keys[i] = ttlEntry[T]{item: d.item}
+	}
+	removed := t.items.DeleteMany(keys)
+	out := make([]T, len(removed))
+	for i, e := range removed {
+		out[i] = e.item
+	}
+
+	return out
+}
@@ -0,0 +1,13 @@
+//go:build goexperiment.arenas
+
+package btree
+
+import "arena"
+
+func (e ttlEntry[T]) DeepCopyWithArena(a *arena.Arena) ttlEntry[T] {
+	return ttlEntry[T]{item: e.item.DeepCopyWithArena(a), deadline: e.deadline}
+}
+
+func (d ttlDeadline[T]) DeepCopyWithArena(a *arena.Arena) ttlDeadline[T] {
+	return ttlDeadline[T]{deadline: d.deadline, item: d.item.DeepCopyWithArena(a), pivot: d.pivot}
+}
@@ -0,0 +1,105 @@
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock which only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestTTLTree(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1e9, 0)}
+	tr := NewTTLWithClock[*testInt](*btreeDegree, clock.Now)
+
+	tr.Set(newTestInt(0))
+	tr.SetWithTTL(newTestInt(1), time.Second)
+	tr.SetWithTTL(newTestInt(2), 2*time.Second)
+	tr.SetWithTTL(newTestInt(3), 3*time.Second)
+
+	if d, ok := tr.Deadline(newTestInt(2)); !ok || !d.Equal(clock.now.Add(2*time.Second)) {
+		t.Fatalf("deadline of 2: %v, %v", d, ok)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if tr.Has(newTestInt(1)) {
+		t.Fatal("1 didn't expire")
+	}
+	if tr.Len() != 3 {
+		t.Fatalf("len %v after lazy expiry, want 3", tr.Len())
+	}
+	if _, ok := tr.Get(newTestInt(0)); !ok {
+		t.Fatal("0 expired")
+	}
+
+	// Replacing an item moves its deadline.
+	if old, ok := tr.SetWithTTL(newTestInt(3), 10*time.Second); !ok || *old != 3 {
+		t.Fatalf("replace 3: %v, %v", old, ok)
+	}
+	clock.now = clock.now.Add(5 * time.Second)
+	var got []int
+	tr.Ascend(func(item *testInt) bool {
+		got = append(got, int(*item))
+		return true
+	})
+	if want := []int{0, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ascend %v, want %v", got, want)
+	}
+	if swept := tr.Sweep(clock.now); len(swept) != 1 || *swept[0] != 2 {
+		t.Fatalf("swept %v, want [2]", swept)
+	}
+	if tr.Len() != 2 || tr.deadlines.Len() != 1 {
+		t.Fatalf("len %v and %v deadlines, want 2 and 1", tr.Len(), tr.deadlines.Len())
+	}
+
+	// An expired item can't be deleted nor replaced.
+	clock.now = clock.now.Add(5 * time.Second)
+	if _, ok := tr.SetWithTTL(newTestInt(3), time.Second); ok {
+		t.Fatal("replaced expired 3")
+	}
+	clock.now = clock.now.Add(time.Second)
+	if _, ok := tr.Delete(newTestInt(3)); ok {
+		t.Fatal("deleted expired 3")
+	}
+	if tr.Len() != 1 || tr.deadlines.Len() != 0 {
+		t.Fatalf("len %v and %v deadlines, want 1 and 0", tr.Len(), tr.deadlines.Len())
+	}
+}
+
+func TestTTLTreeSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1e9, 0)}
+	tr := NewTTLWithClock[*testInt](*btreeDegree, clock.Now)
+	deadlines := map[int]time.Time{}
+	for i := 0; i < 1000; i++ {
+		v := rand.Intn(500)
+		ttl := time.Duration(rand.Intn(100)) * time.Second
+		tr.SetWithTTL(newTestInt(v), ttl)
+		deadlines[v] = clock.now.Add(ttl)
+	}
+	for step := 0; step <= 100; step += 10 {
+		now := clock.now.Add(time.Duration(step) * time.Second)
+		var want []int
+		for v := 0; v < 500; v++ {
+			if d, ok := deadlines[v]; ok && !now.Before(d) {
+				want = append(want, v)
+				delete(deadlines, v)
+			}
+		}
+		var got []int
+		for _, item := range tr.Sweep(now) {
+			got = append(got, int(*item))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("sweep at +%vs: got %v, want %v", step, got, want)
+		}
+		if tr.Len() != len(deadlines) || tr.deadlines.Len() != len(deadlines) {
+			t.Fatalf("len %v and %v deadlines, want %v", tr.Len(), tr.deadlines.Len(), len(deadlines))
+		}
+	}
+}